@@ -0,0 +1,19 @@
+module github.com/ddelpero/secureconfig/awsssm
+
+go 1.24
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.47.1
+	github.com/aws/aws-sdk-go-v2/service/ssm v1.78.1
+	github.com/ddelpero/secureconfig v1.1.2
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.5.4 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.8.4 // indirect
+	github.com/aws/smithy-go v1.28.1 // indirect
+	golang.org/x/crypto v0.21.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+)
+
+replace github.com/ddelpero/secureconfig => ../secureconfig
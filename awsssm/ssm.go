@@ -0,0 +1,55 @@
+// Package awsssm imports parameters from AWS SSM Parameter Store into a
+// secureconfig file. It lives in its own module so the core package keeps
+// no AWS dependency.
+package awsssm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/ddelpero/secureconfig"
+)
+
+// ImportFromSSM lists every parameter under path, decrypting SecureStrings,
+// and stores them in c under dotted keys derived from their names, so
+// /myapp/prod/db/password imported from /myapp/prod becomes db.password.
+// Every parameter is listed before anything is stored, and all of them are
+// written together, so a failed import leaves c unchanged. client is
+// normally an *ssm.Client.
+func ImportFromSSM(ctx context.Context, c *secureconfig.Config, client ssm.GetParametersByPathAPIClient, path string) error {
+	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+
+	pairs := make(map[string]string)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list parameters under %s: %v", path, err)
+		}
+		for _, p := range page.Parameters {
+			name := aws.ToString(p.Name)
+			key := KeyForParameter(path, name)
+			if key == "" {
+				return fmt.Errorf("parameter %s maps to an empty key", name)
+			}
+			pairs[key] = aws.ToString(p.Value)
+		}
+	}
+	if err := c.StoreAll(pairs); err != nil {
+		return fmt.Errorf("failed to store parameters under %s: %v", path, err)
+	}
+	return nil
+}
+
+// KeyForParameter maps an SSM parameter name to a dotted key relative to path
+func KeyForParameter(path, name string) string {
+	rel := strings.TrimPrefix(name, strings.TrimSuffix(path, "/"))
+	rel = strings.Trim(rel, "/")
+	return strings.ReplaceAll(rel, "/", ".")
+}
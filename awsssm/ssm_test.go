@@ -0,0 +1,112 @@
+package awsssm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/ddelpero/secureconfig"
+)
+
+// fakeSSM serves pages of parameters, failing with err once the pages run out
+type fakeSSM struct {
+	pages [][]types.Parameter
+	err   error
+	input []*ssm.GetParametersByPathInput
+}
+
+func (f *fakeSSM) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	f.input = append(f.input, in)
+	page := len(f.input) - 1
+	if page >= len(f.pages) {
+		return nil, f.err
+	}
+	out := &ssm.GetParametersByPathOutput{Parameters: f.pages[page]}
+	if page+1 < len(f.pages) || f.err != nil {
+		out.NextToken = aws.String("next")
+	}
+	return out, nil
+}
+
+func param(name, value string) types.Parameter {
+	return types.Parameter{Name: aws.String(name), Value: aws.String(value)}
+}
+
+func newTestConfig(t *testing.T) *secureconfig.Config {
+	t.Helper()
+	c, err := secureconfig.NewConfigWithFile(filepath.Join(t.TempDir(), "config.bin"))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	return c
+}
+
+func TestImportFromSSM(t *testing.T) {
+	c := newTestConfig(t)
+	if err := c.Store("existing", "kept"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	client := &fakeSSM{pages: [][]types.Parameter{
+		{param("/myapp/prod/db/password", "s3cret"), param("/myapp/prod/db/user", "admin")},
+		{param("/myapp/prod/api/keys/primary", "abc123")},
+	}}
+
+	if err := ImportFromSSM(context.Background(), c, client, "/myapp/prod/"); err != nil {
+		t.Fatalf("ImportFromSSM: %v", err)
+	}
+
+	in := client.input[0]
+	if aws.ToString(in.Path) != "/myapp/prod/" || !aws.ToBool(in.Recursive) || !aws.ToBool(in.WithDecryption) {
+		t.Errorf("request = path %q recursive %v decryption %v, want a recursive decrypted listing of the path",
+			aws.ToString(in.Path), aws.ToBool(in.Recursive), aws.ToBool(in.WithDecryption))
+	}
+	want := map[string]string{
+		"db.password":      "s3cret",
+		"db.user":          "admin",
+		"api.keys.primary": "abc123",
+		"existing":         "kept",
+	}
+	for k, v := range want {
+		if got, err := c.Retrieve(k); err != nil || got != v {
+			t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, v)
+		}
+	}
+	if keys, _ := c.ListKeys(); len(keys) != len(want) {
+		t.Errorf("ListKeys = %v, want %d keys", keys, len(want))
+	}
+}
+
+func TestImportFromSSMFailureStoresNothing(t *testing.T) {
+	c := newTestConfig(t)
+	client := &fakeSSM{
+		pages: [][]types.Parameter{{param("/myapp/db/password", "s3cret")}},
+		err:   errors.New("throttled"),
+	}
+
+	if err := ImportFromSSM(context.Background(), c, client, "/myapp"); err == nil {
+		t.Fatal("ImportFromSSM succeeded despite a failed page")
+	}
+	if keys, _ := c.ListKeys(); len(keys) != 0 {
+		t.Errorf("ListKeys = %v after a failed import, want none", keys)
+	}
+}
+
+func TestKeyForParameter(t *testing.T) {
+	tests := []struct {
+		path, name, want string
+	}{
+		{"/myapp/prod", "/myapp/prod/db/password", "db.password"},
+		{"/myapp/prod/", "/myapp/prod/db/password", "db.password"},
+		{"/", "/top", "top"},
+		{"/myapp/prod", "/myapp/prod", ""},
+	}
+	for _, tt := range tests {
+		if got := KeyForParameter(tt.path, tt.name); got != tt.want {
+			t.Errorf("KeyForParameter(%q, %q) = %q, want %q", tt.path, tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,4 +4,9 @@ go 1.19
 
 require github.com/ddelpero/secureconfig v1.1.2
 
+require (
+	golang.org/x/crypto v0.21.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+)
+
 replace github.com/ddelpero/secureconfig => ../secureconfig
@@ -1,32 +1,95 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/ddelpero/secureconfig"
 )
 
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: secureconfig-cli <key> <value>")
+	fmt.Fprintln(w, "       secureconfig-cli --get [--max-bytes N] <key>")
+	fmt.Fprintln(w, "Example: secureconfig-cli database.password mySecretPassword")
+	fmt.Fprintln(w, "Example: secureconfig-cli --get --max-bytes 64 database.password")
+}
+
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: secureconfig-cli <key> <value>")
-		fmt.Println("Example: secureconfig-cli database.password mySecretPassword")
-		os.Exit(1)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	// Only --get takes flags, so a <key> or <value> starting with "-" stores as before
+	if len(args) > 0 && args[0] == "--get" {
+		fs := flag.NewFlagSet("secureconfig-cli", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		fs.Usage = func() { usage(stdout) }
+		maxBytes := fs.Int("max-bytes", 0, "truncate the value to at most N bytes (0 for no limit)")
+		if err := fs.Parse(args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			return 2
+		}
+		return get(fs.Args(), *maxBytes, stdout, stderr)
+	}
+
+	if len(args) < 2 {
+		usage(stdout)
+		return 1
 	}
 
-	key := os.Args[1]
-	value := os.Args[2]
+	key := args[0]
+	value := args[1]
 
 	config, err := secureconfig.NewConfig()
 	if err != nil {
-		fmt.Printf("Error initializing config: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stdout, "Error initializing config: %v\n", err)
+		return 1
 	}
 
 	if err := config.Store(key, value); err != nil {
-		fmt.Printf("Error storing value: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stdout, "Error storing value: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Successfully stored encrypted value for key: %s\n", key)
+	return 0
+}
+
+// get prints a decrypted value, optionally capped to maxBytes
+func get(args []string, maxBytes int, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		usage(stdout)
+		return 1
 	}
+	key := args[0]
 
-	fmt.Printf("Successfully stored encrypted value for key: %s\n", key)
+	// Opening a missing file would create one with a new key
+	if _, err := os.Stat(secureconfig.ConfigFile); err != nil {
+		fmt.Fprintf(stdout, "Error reading config: %v\n", err)
+		return 1
+	}
+	config, err := secureconfig.NewConfig()
+	if err != nil {
+		fmt.Fprintf(stdout, "Error initializing config: %v\n", err)
+		return 1
+	}
+
+	value, truncated, err := config.RetrieveLimited(key, maxBytes)
+	if err != nil {
+		fmt.Fprintf(stdout, "Error retrieving value: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, value)
+	if truncated {
+		// The cut is moved back to a rune boundary, so report what was printed
+		fmt.Fprintf(stderr, "[value truncated to %d bytes]\n", len(value))
+	}
+	return 0
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// inTempDir runs the test from an empty directory, where the CLI keeps its config
+func inTempDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// runCLI runs the CLI with args and returns its exit code and output
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestStoreKeyAndValueStartingWithDash(t *testing.T) {
+	inTempDir(t)
+	if code, out, _ := runCLI(t, "-db.password", "--secret"); code != 0 {
+		t.Fatalf("store exited %d: %s", code, out)
+	}
+	// With --get, "--" ends the flags so the key can start with "-" too
+	code, out, _ := runCLI(t, "--get", "--", "-db.password")
+	if code != 0 {
+		t.Fatalf("--get exited %d: %s", code, out)
+	}
+	if got := strings.TrimSuffix(out, "\n"); got != "--secret" {
+		t.Errorf("--get printed %q, want --secret", got)
+	}
+}
+
+func TestGetReportsTruncatedLength(t *testing.T) {
+	inTempDir(t)
+	if code, out, _ := runCLI(t, "greeting", "héllo"); code != 0 {
+		t.Fatalf("store exited %d: %s", code, out)
+	}
+
+	// The cut at 2 bytes falls inside é, so only "h" is printed
+	code, out, errOut := runCLI(t, "--get", "--max-bytes", "2", "greeting")
+	if code != 0 {
+		t.Fatalf("--get exited %d: %s", code, out)
+	}
+	if out != "h\n" {
+		t.Errorf("--get printed %q, want h", out)
+	}
+	if want := "[value truncated to 1 bytes]\n"; errOut != want {
+		t.Errorf("notice = %q, want %q", errOut, want)
+	}
+
+	code, out, errOut = runCLI(t, "--get", "greeting")
+	if code != 0 || out != "héllo\n" || errOut != "" {
+		t.Errorf("untruncated --get = %d, %q, %q; want the whole value and no notice", code, out, errOut)
+	}
+}
+
+func TestGetWithoutConfig(t *testing.T) {
+	inTempDir(t)
+	if code, _, _ := runCLI(t, "--get", "missing"); code != 1 {
+		t.Errorf("--get without a config exited %d, want 1", code)
+	}
+	if _, err := os.Stat("config"); !os.IsNotExist(err) {
+		t.Errorf("--get created a config file: %v", err)
+	}
+}
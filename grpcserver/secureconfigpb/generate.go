@@ -0,0 +1,5 @@
+// Package secureconfigpb holds the protobuf and gRPC code generated from
+// secureconfig.proto.
+package secureconfigpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative secureconfig.proto
@@ -0,0 +1,142 @@
+// Package grpcserver serves a secureconfig store over gRPC so non-Go services
+// can use it. It lives in its own module so the core package keeps no gRPC
+// dependency. Connections require TLS and every call must carry the bearer
+// token the server was started with.
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/ddelpero/secureconfig"
+	pb "github.com/ddelpero/secureconfig/grpcserver/secureconfigpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures the server
+type Option func(*options)
+
+type options struct {
+	tlsConfig *tls.Config
+	token     string
+}
+
+// WithTLSConfig sets the TLS configuration the server listens with. Required.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithAuthToken sets the bearer token clients must send in the
+// "authorization" metadata as "Bearer <token>". Required.
+func WithAuthToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// NewServer returns a gRPC server exposing c, for callers that manage the
+// server's lifecycle themselves
+func NewServer(c *secureconfig.Config, opts ...Option) (*grpc.Server, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.tlsConfig == nil {
+		return nil, errors.New("a TLS configuration is required")
+	}
+	if o.token == "" {
+		return nil, errors.New("an auth token is required")
+	}
+
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(o.tlsConfig)),
+		grpc.UnaryInterceptor(authInterceptor(o.token)),
+	)
+	pb.RegisterSecureConfigServer(s, &service{config: c})
+	return s, nil
+}
+
+// ServeGRPC serves c on lis until the listener fails or is closed
+func ServeGRPC(c *secureconfig.Config, lis net.Listener, opts ...Option) error {
+	s, err := NewServer(c, opts...)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// authInterceptor rejects calls without the expected bearer token
+func authInterceptor(token string) grpc.UnaryServerInterceptor {
+	want := []byte("Bearer " + token)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, got := range md.Get("authorization") {
+			if subtle.ConstantTimeCompare([]byte(got), want) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+}
+
+// service implements the SecureConfig gRPC service on a Config
+type service struct {
+	pb.UnimplementedSecureConfigServer
+	config *secureconfig.Config
+}
+
+func (s *service) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	value, err := s.config.Retrieve(req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetResponse{Value: value}, nil
+}
+
+func (s *service) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	keys, err := s.config.ListKeys()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListResponse{Keys: keys}, nil
+}
+
+func (s *service) Set(ctx context.Context, req *pb.SetRequest) (*pb.SetResponse, error) {
+	if strings.TrimSpace(req.GetKey()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "key must not be empty")
+	}
+	if err := s.config.StoreWithContext(ctx, req.GetKey(), req.GetValue()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SetResponse{}, nil
+}
+
+func (s *service) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	if err := s.config.Delete(req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteResponse{}, nil
+}
+
+// toStatus maps Config errors to gRPC status codes
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, secureconfig.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, secureconfig.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, secureconfig.ErrWeakSecret):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,165 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ddelpero/secureconfig"
+	pb "github.com/ddelpero/secureconfig/grpcserver/secureconfigpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "test-token"
+
+// startServer serves a fresh config over an in-memory TLS connection and
+// returns a client for it
+func startServer(t *testing.T, opts ...secureconfig.Option) pb.SecureConfigClient {
+	t.Helper()
+	c, err := secureconfig.NewConfigWithFile(filepath.Join(t.TempDir(), "config.bin"), opts...)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	serverTLS, pool := testCertificate(t)
+	s, err := NewServer(c, WithTLSConfig(serverTLS), WithAuthToken(testToken))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool, ServerName: "localhost"})),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewSecureConfigClient(conn)
+}
+
+// testCertificate issues a self-signed certificate for localhost
+func testCertificate(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+}
+
+func TestService(t *testing.T) {
+	client := startServer(t)
+	ctx := authorized()
+
+	for k, v := range map[string]string{"db.password": "hunter2", "api.token": "tok"} {
+		if _, err := client.Set(ctx, &pb.SetRequest{Key: k, Value: v}); err != nil {
+			t.Fatalf("Set(%q): %v", k, err)
+		}
+	}
+	got, err := client.Get(ctx, &pb.GetRequest{Key: "db.password"})
+	if err != nil || got.GetValue() != "hunter2" {
+		t.Errorf("Get = %q, %v; want hunter2", got.GetValue(), err)
+	}
+	list, err := client.List(ctx, &pb.ListRequest{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	keys := list.GetKeys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "api.token" || keys[1] != "db.password" {
+		t.Errorf("List = %v, want [api.token db.password]", keys)
+	}
+
+	if _, err := client.Delete(ctx, &pb.DeleteRequest{Key: "api.token"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := client.Get(ctx, &pb.GetRequest{Key: "api.token"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get after Delete = %v, want NotFound", err)
+	}
+	if _, err := client.Delete(ctx, &pb.DeleteRequest{Key: "api.token"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Delete of a missing key = %v, want NotFound", err)
+	}
+	if _, err := client.Set(ctx, &pb.SetRequest{Key: " ", Value: "v"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Set with an empty key = %v, want InvalidArgument", err)
+	}
+}
+
+func TestServiceRejectsUnauthenticatedCalls(t *testing.T) {
+	client := startServer(t)
+
+	contexts := map[string]context.Context{
+		"no token":    context.Background(),
+		"wrong token": metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"),
+		"bare token":  metadata.AppendToOutgoingContext(context.Background(), "authorization", testToken),
+	}
+	for name, ctx := range contexts {
+		if _, err := client.Get(ctx, &pb.GetRequest{Key: "k"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: Get = %v, want Unauthenticated", name, err)
+		}
+		if _, err := client.Set(ctx, &pb.SetRequest{Key: "k", Value: "v"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: Set = %v, want Unauthenticated", name, err)
+		}
+	}
+}
+
+func TestServiceRejectsWeakSecrets(t *testing.T) {
+	client := startServer(t, secureconfig.WithMinEntropyBits(40))
+
+	_, err := client.Set(authorized(), &pb.SetRequest{Key: "db.password", Value: "changeme"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Set of a weak secret = %v, want InvalidArgument", err)
+	}
+}
+
+func TestNewServerRequiresTLSAndToken(t *testing.T) {
+	serverTLS, _ := testCertificate(t)
+	if _, err := NewServer(nil, WithAuthToken(testToken)); err == nil {
+		t.Error("NewServer accepted a missing TLS configuration")
+	}
+	if _, err := NewServer(nil, WithTLSConfig(serverTLS)); err == nil {
+		t.Error("NewServer accepted a missing auth token")
+	}
+}
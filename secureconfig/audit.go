@@ -0,0 +1,86 @@
+package secureconfig
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Change operations recorded in the audit log and change events
+const (
+	OpStore      = "store"
+	OpDelete     = "delete"
+	OpSoftDelete = "soft-delete"
+	OpUndelete   = "undelete"
+)
+
+// auditEntry is one JSON line in the audit log. It never carries values.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	Op     string    `json:"op"`
+	Key    string    `json:"key"`
+	Actor  string    `json:"actor,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+type auditContextKey int
+
+const (
+	actorKey auditContextKey = iota
+	reasonKey
+)
+
+// WithAuditLog writes one JSON line per change to w, recording the operation,
+// key, actor and reason but never the value
+func WithAuditLog(w io.Writer) Option {
+	return func(c *Config) {
+		c.audit = w
+	}
+}
+
+// ContextWithActor returns a context carrying who is making a change
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ContextWithReason returns a context carrying why a change is made
+func ContextWithReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonKey, reason)
+}
+
+// ActorFromContext returns the actor set with ContextWithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// ReasonFromContext returns the reason set with ContextWithReason
+func ReasonFromContext(ctx context.Context) string {
+	reason, _ := ctx.Value(reasonKey).(string)
+	return reason
+}
+
+// recordChange notifies subscribers and appends an entry to the audit log if
+// one is configured. The change is already on disk by then, so a failed
+// audit write is logged rather than reported as a failed change.
+func (c *Config) recordChange(ctx context.Context, op, key string) {
+	c.publish(ChangeEvent{Key: key, Op: op})
+	if c.audit == nil {
+		return
+	}
+	line, err := json.Marshal(auditEntry{
+		Time:   c.now(),
+		Op:     op,
+		Key:    key,
+		Actor:  ActorFromContext(ctx),
+		Reason: ReasonFromContext(ctx),
+	})
+	if err != nil {
+		c.logger.Printf("warning: failed to encode audit entry for %s %s: %v", op, key, err)
+		return
+	}
+	if _, err := c.audit.Write(append(line, '\n')); err != nil {
+		c.logger.Printf("warning: failed to write audit entry for %s %s: %v", op, key, err)
+	}
+}
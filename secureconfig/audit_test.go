@@ -0,0 +1,71 @@
+package secureconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestAuditCarriesActorAndReason(t *testing.T) {
+	var audit bytes.Buffer
+	c, _ := newTestConfig(t, WithAuditLog(&audit))
+
+	ctx := ContextWithReason(ContextWithActor(context.Background(), "alice"), "rotate creds")
+	if err := c.StoreWithContext(ctx, "db.password", "s3cret-value"); err != nil {
+		t.Fatalf("StoreWithContext: %v", err)
+	}
+	if err := c.Delete("db.password"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if strings.Contains(audit.String(), "s3cret-value") {
+		t.Fatal("audit log contains the value")
+	}
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d audit lines, want 2: %q", len(lines), audit.String())
+	}
+
+	var store, del auditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &store); err != nil {
+		t.Fatalf("bad audit line: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &del); err != nil {
+		t.Fatalf("bad audit line: %v", err)
+	}
+	want := auditEntry{Time: store.Time, Op: OpStore, Key: "db.password", Actor: "alice", Reason: "rotate creds"}
+	if store != want {
+		t.Errorf("store entry = %+v, want %+v", store, want)
+	}
+	if del.Op != OpDelete || del.Key != "db.password" || del.Actor != "" || del.Reason != "" {
+		t.Errorf("delete entry = %+v", del)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestAuditWriteFailureIsLogged(t *testing.T) {
+	var logs bytes.Buffer
+	c, path := newTestConfig(t, WithAuditLog(failingWriter{}), WithLogger(log.New(&logs, "", 0)))
+
+	if err := c.Store("a", "1"); err != nil {
+		t.Fatalf("Store returned the audit failure: %v", err)
+	}
+	if !strings.Contains(logs.String(), "disk full") {
+		t.Errorf("audit failure not logged, got %q", logs.String())
+	}
+
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if v, err := reopened.Retrieve("a"); err != nil || v != "1" {
+		t.Errorf("Retrieve = %q, %v; want the stored value", v, err)
+	}
+}
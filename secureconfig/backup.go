@@ -0,0 +1,115 @@
+package secureconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WithBackupOnWrite keeps the last keep versions of the file as
+// <file>.bak.1 (newest) through <file>.bak.<keep>, rotating them before every
+// write. Older backups beyond keep are removed. If the file is later found
+// corrupted on open, it is moved aside to <file>.corrupt and the config is
+// restored from the newest usable backup. A keep below 1 disables backups.
+func WithBackupOnWrite(keep int) Option {
+	return func(c *Config) {
+		if keep < 0 {
+			keep = 0
+		}
+		c.backups = keep
+	}
+}
+
+// backupPath returns where the n-th newest backup of filename is kept
+func backupPath(filename string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", filename, n)
+}
+
+// corruptPath returns where a corrupted filename is kept after recovery
+func corruptPath(filename string) string {
+	return filename + ".corrupt"
+}
+
+// backupFile shifts the existing backups of filename along by one, copies
+// filename in as the newest and prunes the backups beyond keep
+func backupFile(filename string, keep int) error {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := pruneBackups(filename, keep-1); err != nil {
+		return err
+	}
+	for n := keep - 1; n >= 1; n-- {
+		err := os.Rename(backupPath(filename, n), backupPath(filename, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(filename, 1), data)
+}
+
+// pruneBackups removes the backups of filename numbered above keep
+func pruneBackups(filename string, keep int) error {
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(filename) + ".bak."
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+		if err != nil || n <= keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(filepath.Dir(filename), name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverFromBackup loads the newest usable backup of a corrupted file, moves
+// the corrupted one aside for inspection and writes the backup in its place.
+// cause is the error the primary file failed with.
+func (c *Config) recoverFromBackup(filename string, cause error) error {
+	var failures []string
+	for n := 1; n <= c.backups; n++ {
+		path := backupPath(filename, n)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		if _, err := c.parseDB(data); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		// Restore directly so the corrupted file does not enter the rotation
+		corrupt := corruptPath(filename)
+		if err := os.Rename(filename, corrupt); err != nil {
+			return fmt.Errorf("failed to move corrupted config file aside: %v", err)
+		}
+		if err := writeFileAtomic(filename, data); err != nil {
+			return fmt.Errorf("failed to restore config file from backup: %v", err)
+		}
+		c.logger.Printf("warning: %s was corrupted (%v), moved to %s and recovered from %s", filename, cause, corrupt, path)
+		return nil
+	}
+	if len(failures) == 0 {
+		return fmt.Errorf("%w (no backup found)", cause)
+	}
+	return fmt.Errorf("%w (no usable backup: %s)", cause, strings.Join(failures, "; "))
+}
@@ -0,0 +1,145 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBackupRotation(t *testing.T) {
+	const keep = 3
+	c, path := newTestConfig(t, WithBackupOnWrite(keep))
+
+	// versions[i] is the file after the i-th write
+	var versions [][]byte
+	for i := 0; i < 5; i++ {
+		mustStore(t, c, map[string]string{"counter": fmt.Sprint(i)})
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, data)
+	}
+
+	if n := countBackups(t, path); n != keep {
+		t.Fatalf("%d backups after 5 writes, want %d", n, keep)
+	}
+	for n := 1; n <= keep; n++ {
+		data, err := os.ReadFile(backupPath(path, n))
+		if err != nil {
+			t.Fatalf("backup %d: %v", n, err)
+		}
+		// .bak.1 is the file before the last write, .bak.2 before that
+		if want := versions[len(versions)-1-n]; !bytes.Equal(data, want) {
+			t.Errorf("backup %d does not hold the file from %d writes ago", n, n)
+		}
+		b, err := NewConfigWithFile(backupPath(path, n))
+		if err != nil {
+			t.Fatalf("backup %d does not open: %v", n, err)
+		}
+		if got, _ := b.Retrieve("counter"); got != fmt.Sprint(4-n) {
+			t.Errorf("backup %d holds counter %q, want %d", n, got, 4-n)
+		}
+	}
+
+	// Lowering keep prunes the excess on the next write
+	c, err := NewConfigWithFile(path, WithBackupOnWrite(1))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	mustStore(t, c, map[string]string{"counter": "5"})
+	if n := countBackups(t, path); n != 1 {
+		t.Errorf("%d backups with keep 1, want 1", n)
+	}
+	if data, _ := os.ReadFile(backupPath(path, 1)); !bytes.Equal(data, versions[4]) {
+		t.Error("the only backup does not hold the file before the last write")
+	}
+}
+
+func TestRecoverFromBackup(t *testing.T) {
+	c, path := newTestConfig(t, WithBackupOnWrite(3))
+	mustStore(t, c, map[string]string{"a": "1"})
+	mustStore(t, c, map[string]string{"b": "2"})
+
+	damaged := truncateFile(t, path)
+
+	var logs bytes.Buffer
+	recovered, err := NewConfigWithFile(path, WithBackupOnWrite(3), WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+
+	// .bak.1 holds the file from before the last write, so a is back and the
+	// write of b is lost
+	if got, err := recovered.Retrieve("a"); err != nil || got != "1" {
+		t.Errorf("Retrieve(a) = %q, %v; want 1", got, err)
+	}
+	if _, err := recovered.Retrieve("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Retrieve(b) = %v, want ErrNotFound", err)
+	}
+
+	corrupt, err := os.ReadFile(corruptPath(path))
+	if err != nil {
+		t.Fatalf("corrupted file was not kept: %v", err)
+	}
+	if !bytes.Equal(corrupt, damaged) {
+		t.Error("the kept corrupted file differs from the damaged original")
+	}
+	for _, want := range []string{path + ".corrupt", path + ".bak.1"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("warning does not name %s: %q", want, logs.String())
+		}
+	}
+
+	// The restored file opens without recovery
+	if _, err := NewConfigWithFile(path); err != nil {
+		t.Errorf("restored file does not open: %v", err)
+	}
+}
+
+func TestRecoverFromBackupSkipsDamagedBackups(t *testing.T) {
+	c, path := newTestConfig(t, WithBackupOnWrite(3))
+	mustStore(t, c, map[string]string{"a": "1"})
+	mustStore(t, c, map[string]string{"b": "2"})
+	mustStore(t, c, map[string]string{"c": "3"})
+
+	truncateFile(t, path)
+	truncateFile(t, backupPath(path, 1))
+	recovered, err := NewConfigWithFile(path, WithBackupOnWrite(3), WithLogger(log.New(&bytes.Buffer{}, "", 0)))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if keys, _ := recovered.ListKeys(); len(keys) != 1 {
+		t.Errorf("ListKeys = %v, want only a from .bak.2", keys)
+	}
+}
+
+func TestRecoverFromBackupWithoutUsableBackup(t *testing.T) {
+	_, path := newTestConfig(t, WithBackupOnWrite(3))
+	truncateFile(t, path)
+
+	if _, err := NewConfigWithFile(path, WithBackupOnWrite(3)); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("NewConfigWithFile = %v, want ErrCorrupted", err)
+	}
+	if _, err := os.Stat(corruptPath(path)); !os.IsNotExist(err) {
+		t.Error("corrupted file was moved aside although nothing was restored")
+	}
+}
+
+// truncateFile cuts path to half its length and returns what is left
+func truncateFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[:len(data)/2]
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,101 @@
+package secureconfig
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithValueCacheBytes caches decrypted values so repeated Retrieve calls skip
+// the key scan and decryption. The cache holds at most n bytes of values,
+// evicting the least recently used first; values larger than n are never
+// cached.
+func WithValueCacheBytes(n int64) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.cache = newValueCache(n)
+		}
+	}
+}
+
+// valueCache is an LRU of decrypted values bounded by their total size. A nil
+// *valueCache is a disabled cache.
+type valueCache struct {
+	mu     sync.Mutex
+	budget int64
+	used   int64
+	ll     *list.List // front is most recently used
+	items  map[string]*list.Element
+}
+
+type cacheItem struct {
+	key   string
+	value string
+}
+
+func newValueCache(budget int64) *valueCache {
+	return &valueCache{
+		budget: budget,
+		ll:     list.New(),
+		items:  make(map[string]*list.Element),
+	}
+}
+
+func (vc *valueCache) get(key string) (string, bool) {
+	if vc == nil {
+		return "", false
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	el, ok := vc.items[key]
+	if !ok {
+		return "", false
+	}
+	vc.ll.MoveToFront(el)
+	return el.Value.(*cacheItem).value, true
+}
+
+func (vc *valueCache) put(key, value string) {
+	if vc == nil {
+		return
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.removeLocked(key)
+	size := int64(len(value))
+	if size > vc.budget {
+		return
+	}
+	vc.items[key] = vc.ll.PushFront(&cacheItem{key: key, value: value})
+	vc.used += size
+	for vc.used > vc.budget {
+		vc.removeLocked(vc.ll.Back().Value.(*cacheItem).key)
+	}
+}
+
+func (vc *valueCache) remove(key string) {
+	if vc == nil {
+		return
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.removeLocked(key)
+}
+
+func (vc *valueCache) clear() {
+	if vc == nil {
+		return
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.ll.Init()
+	vc.items = make(map[string]*list.Element)
+	vc.used = 0
+}
+
+func (vc *valueCache) removeLocked(key string) {
+	if el, ok := vc.items[key]; ok {
+		vc.ll.Remove(el)
+		delete(vc.items, key)
+		vc.used -= int64(len(el.Value.(*cacheItem).value))
+	}
+}
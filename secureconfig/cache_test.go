@@ -0,0 +1,80 @@
+package secureconfig
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueCacheStaysWithinBudget(t *testing.T) {
+	const budget = 100
+	vc := newValueCache(budget)
+
+	sizes := []int{10, 60, 1, 35, 99, 5, 50, 20, 0, 45}
+	for i, size := range sizes {
+		vc.put(fmt.Sprintf("k%d", i), strings.Repeat("v", size))
+		if vc.used > budget {
+			t.Fatalf("after caching %d bytes: used = %d, over the budget of %d", size, vc.used, budget)
+		}
+		var total int64
+		for _, el := range vc.items {
+			total += int64(len(el.Value.(*cacheItem).value))
+		}
+		if total != vc.used {
+			t.Fatalf("used = %d, but cached values total %d", vc.used, total)
+		}
+	}
+
+	// The newest values that fit are kept, older ones were evicted
+	for i, want := range map[int]bool{9: true, 8: true, 7: true, 4: false, 1: false} {
+		if _, ok := vc.get(fmt.Sprintf("k%d", i)); ok != want {
+			t.Errorf("k%d cached = %v, want %v", i, ok, want)
+		}
+	}
+
+	vc.put("huge", strings.Repeat("v", budget+1))
+	if _, ok := vc.get("huge"); ok {
+		t.Error("a value larger than the budget was cached")
+	}
+}
+
+func TestValueCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	vc := newValueCache(30)
+	vc.put("a", strings.Repeat("a", 10))
+	vc.put("b", strings.Repeat("b", 10))
+	vc.put("c", strings.Repeat("c", 10))
+	vc.get("a") // a is now newer than b
+
+	vc.put("d", strings.Repeat("d", 15))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true} {
+		if _, ok := vc.get(key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestRetrieveWithValueCache(t *testing.T) {
+	c, _ := newTestConfig(t, WithValueCacheBytes(64))
+	mustStore(t, c, map[string]string{"small": "s", "large": strings.Repeat("x", 100)})
+
+	for i := 0; i < 2; i++ {
+		if got, err := c.Retrieve("large"); err != nil || len(got) != 100 {
+			t.Fatalf("Retrieve(large) = %d bytes, %v", len(got), err)
+		}
+		if got, err := c.Retrieve("small"); err != nil || got != "s" {
+			t.Fatalf("Retrieve(small) = %q, %v", got, err)
+		}
+	}
+	if c.cache.used > 64 {
+		t.Errorf("cache uses %d bytes, over the budget of 64", c.cache.used)
+	}
+	if _, ok := c.cache.get("large"); ok {
+		t.Error("a value larger than the budget was cached")
+	}
+
+	// Writes invalidate the cached value
+	mustStore(t, c, map[string]string{"small": "t"})
+	if got, err := c.Retrieve("small"); err != nil || got != "t" {
+		t.Errorf("Retrieve(small) after Store = %q, %v; want t", got, err)
+	}
+}
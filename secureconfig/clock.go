@@ -0,0 +1,40 @@
+package secureconfig
+
+import "time"
+
+// now returns the current time for entry timestamps. With WithClockSkewCheck
+// stored timestamps are used as a baseline: if the system clock reads earlier
+// than the newest stored timestamp the clock has moved backward, a warning is
+// logged and the baseline is returned instead so timestamps never go back in
+// time.
+func (c *Config) now() time.Time {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	t := c.clock().UTC()
+	if !c.skewCheck {
+		return t
+	}
+	if t.Before(c.baseline) {
+		if !c.skewed {
+			c.skewed = true
+			c.logger.Printf("warning: system clock is %v behind the newest stored timestamp, using stored time", c.baseline.Sub(t))
+		}
+		return c.baseline
+	}
+	c.skewed = false
+	c.baseline = t
+	return t
+}
+
+// loadBaseline takes the newest stored timestamp as the clock baseline
+func (c *Config) loadBaseline() {
+	for _, meta := range c.meta {
+		if meta.Updated.After(c.baseline) {
+			c.baseline = meta.Updated
+		}
+		if meta.Created.After(c.baseline) {
+			c.baseline = meta.Created
+		}
+	}
+}
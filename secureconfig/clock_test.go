@@ -0,0 +1,63 @@
+package secureconfig
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for tests
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func TestClockSkewCheck(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}
+	var logs bytes.Buffer
+	c, path := newTestConfig(t, WithClock(clock.now), WithClockSkewCheck(), WithLogger(log.New(&logs, "", 0)))
+	mustStore(t, c, map[string]string{"a": "1"})
+	stored := clock.t
+
+	// Reopen with the clock an hour behind the stored timestamp
+	clock.t = stored.Add(-time.Hour)
+	c, err := NewConfigWithFile(path, WithClock(clock.now), WithClockSkewCheck(), WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if !strings.Contains(logs.String(), "behind the newest stored timestamp") {
+		t.Fatalf("no skew warning logged, got %q", logs.String())
+	}
+
+	mustStore(t, c, map[string]string{"b": "2"})
+	if got := c.meta["b"].Created; !got.Equal(stored) {
+		t.Errorf("timestamp while skewed = %v, want the stored baseline %v", got, stored)
+	}
+	if strings.Count(logs.String(), "warning") != 1 {
+		t.Errorf("warning logged more than once while skewed: %q", logs.String())
+	}
+
+	// Once the clock catches up, it is used again
+	clock.t = stored.Add(time.Hour)
+	mustStore(t, c, map[string]string{"c": "3"})
+	if got := c.meta["c"].Created; !got.Equal(clock.t) {
+		t.Errorf("timestamp after recovery = %v, want %v", got, clock.t)
+	}
+}
+
+func TestClockSkewCheckOff(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}
+	var logs bytes.Buffer
+	c, _ := newTestConfig(t, WithClock(clock.now), WithLogger(log.New(&logs, "", 0)))
+	mustStore(t, c, map[string]string{"a": "1"})
+
+	clock.t = clock.t.Add(-time.Hour)
+	mustStore(t, c, map[string]string{"b": "2"})
+	if got := c.meta["b"].Created; !got.Equal(clock.t) {
+		t.Errorf("timestamp = %v, want the clock's %v", got, clock.t)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("unexpected log output %q", logs.String())
+	}
+}
@@ -0,0 +1,48 @@
+package secureconfig
+
+import (
+	"fmt"
+	"math"
+)
+
+// WithMinEntropyBits rejects stores of values whose EntropyBits estimate is
+// below n, catching placeholders such as "changeme" before they ship. The
+// failing store returns an error wrapping ErrWeakSecret.
+func WithMinEntropyBits(n float64) Option {
+	return func(c *Config) {
+		c.minEntropy = n
+	}
+}
+
+// EntropyBits estimates the entropy of value in bits as its length in runes
+// times the Shannon entropy of its own character distribution. The estimate
+// is deterministic and uses no dictionary, so it penalises short and
+// repetitive values but cannot spot a common word spelled with varied
+// characters. An empty value has zero bits.
+func EntropyBits(value string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range value {
+		counts[r]++
+		n++
+	}
+
+	perRune := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		perRune -= p * math.Log2(p)
+	}
+	return perRune * float64(n)
+}
+
+// checkEntropy enforces WithMinEntropyBits, the error names the key but never
+// the value
+func (c *Config) checkEntropy(key, value string) error {
+	if c.minEntropy <= 0 {
+		return nil
+	}
+	if bits := EntropyBits(value); bits < c.minEntropy {
+		return fmt.Errorf("%w: value for %s has an estimated %.1f bits, at least %.1f required", ErrWeakSecret, key, bits, c.minEntropy)
+	}
+	return nil
+}
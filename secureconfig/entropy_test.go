@@ -0,0 +1,56 @@
+package secureconfig
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEntropyBits(t *testing.T) {
+	tests := map[string]float64{
+		"":         0,
+		"aaaa":     0,
+		"ab":       2,
+		"abcd":     8,
+		"aabb":     4,
+		"01234567": 24,
+	}
+	for value, want := range tests {
+		if got := EntropyBits(value); math.Abs(got-want) > 1e-9 {
+			t.Errorf("EntropyBits(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestMinEntropyBits(t *testing.T) {
+	c, _ := newTestConfig(t, WithMinEntropyBits(40))
+
+	err := c.Store("db.password", "changeme")
+	if !errors.Is(err, ErrWeakSecret) {
+		t.Fatalf("Store of a weak value = %v, want ErrWeakSecret", err)
+	}
+	if !strings.Contains(err.Error(), "db.password") || strings.Contains(err.Error(), "changeme") {
+		t.Errorf("error %q should name the key but not the value", err)
+	}
+	if _, err := c.Retrieve("db.password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("weak value was stored: Retrieve = %v", err)
+	}
+
+	strong := "Zq8#vL2!pR6@xW4$"
+	if err := c.Store("db.password", strong); err != nil {
+		t.Fatalf("Store of a strong value: %v", err)
+	}
+	if got, err := c.Retrieve("db.password"); err != nil || got != strong {
+		t.Errorf("Retrieve = %q, %v; want the strong value", got, err)
+	}
+	if err := c.ReplaceAll(map[string]string{"db.password": "aaaaaaaaaaaa"}); !errors.Is(err, ErrWeakSecret) {
+		t.Errorf("ReplaceAll with a weak value = %v, want ErrWeakSecret", err)
+	}
+
+	// Without the option any value is accepted
+	lax, _ := newTestConfig(t)
+	if err := lax.Store("db.password", "changeme"); err != nil {
+		t.Errorf("Store without WithMinEntropyBits: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package secureconfig
+
+import "sync"
+
+// subscriberBuffer is the channel capacity given to each subscriber
+const subscriberBuffer = 64
+
+// ChangeEvent reports a change to a key. It never carries the value.
+type ChangeEvent struct {
+	Key string
+	Op  string // OpStore, OpDelete, OpSoftDelete or OpUndelete
+}
+
+// subscribers tracks the channels registered with Subscribe
+type subscribers struct {
+	mu   sync.Mutex
+	next int
+	chs  map[int]chan ChangeEvent
+}
+
+// Subscribe returns a channel receiving an event for every change made
+// through this Config, and a func that unsubscribes and closes the
+// channel. Events are dropped for a subscriber whose buffer is full rather
+// than blocking writers.
+func (c *Config) Subscribe() (<-chan ChangeEvent, func()) {
+	c.subs.mu.Lock()
+	defer c.subs.mu.Unlock()
+	if c.subs.chs == nil {
+		c.subs.chs = make(map[int]chan ChangeEvent)
+	}
+	id := c.subs.next
+	c.subs.next++
+	ch := make(chan ChangeEvent, subscriberBuffer)
+	c.subs.chs[id] = ch
+
+	return ch, func() {
+		c.subs.mu.Lock()
+		defer c.subs.mu.Unlock()
+		if ch, ok := c.subs.chs[id]; ok {
+			delete(c.subs.chs, id)
+			close(ch)
+		}
+	}
+}
+
+// publish delivers an event to every subscriber without blocking
+func (c *Config) publish(ev ChangeEvent) {
+	c.subs.mu.Lock()
+	defer c.subs.mu.Unlock()
+	for _, ch := range c.subs.chs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package secureconfig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	c, _ := newTestConfig(t)
+	events, unsubscribe := c.Subscribe()
+
+	mustStore(t, c, map[string]string{"a": "secret"})
+	if err := c.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, want := range []ChangeEvent{{Key: "a", Op: OpStore}, {Key: "a", Op: OpDelete}} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("event = %+v, want %+v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event for %+v", want)
+		}
+	}
+
+	unsubscribe()
+	mustStore(t, c, map[string]string{"b": "1"})
+	if ev, ok := <-events; ok {
+		t.Errorf("event %+v delivered after unsubscribing", ev)
+	}
+	unsubscribe() // a second call is harmless
+}
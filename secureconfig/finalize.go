@@ -0,0 +1,43 @@
+package secureconfig
+
+import "time"
+
+// Finalize seals the config as an immutable snapshot. A marker recording the
+// time is written into the file, after which every write returns
+// ErrReadOnly, both on this Config and whenever the file is opened again.
+// SaveAs still copies the sealed snapshot, marker included, unless the
+// handle is read-only for another reason, as with NewConfigFromURL or
+// OpenWithKeys.
+//
+// The seal stops writes through this package. It is not tamper evidence,
+// since anyone holding the key can still rewrite the file by other means.
+func (c *Config) Finalize() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+
+	c.DB[finalEntry] = c.now().Format(time.RFC3339Nano)
+	if err := c.writeSecretsFile(); err != nil {
+		delete(c.DB, finalEntry)
+		return err
+	}
+	c.readOnly, c.sealed = true, true
+	return nil
+}
+
+// Finalized reports whether the config has been sealed with Finalize
+func (c *Config) Finalized() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.finalized()
+}
+
+func (c *Config) finalized() bool {
+	_, ok := c.DB[finalEntry]
+	return ok
+}
@@ -0,0 +1,99 @@
+package secureconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFinalizeSealsReopenedFile(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "1", "b": "2"})
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	newKey := bytes.Repeat([]byte{0x5a}, 32)
+	for _, c := range []*Config{c, reopened} {
+		if !c.Finalized() {
+			t.Error("Finalized = false, want true")
+		}
+		writes := map[string]func() error{
+			"Store":      func() error { return c.Store("c", "3") },
+			"Delete":     func() error { return c.Delete("a") },
+			"SoftDelete": func() error { return c.SoftDelete("a") },
+			"ReplaceAll": func() error { return c.ReplaceAll(map[string]string{"c": "3"}) },
+			"StoreAll":   func() error { return c.StoreAll(map[string]string{"c": "3"}) },
+			"SetKeyID":   func() error { return c.SetKeyID("prod") },
+			"Rekey":      func() error { return c.Rekey(newKey) },
+		}
+		for name, write := range writes {
+			if err := write(); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("%s on a finalized config = %v, want ErrReadOnly", name, err)
+			}
+		}
+		if got, err := c.Retrieve("a"); err != nil || got != "1" {
+			t.Errorf("Retrieve(a) = %q, %v; want 1", got, err)
+		}
+	}
+
+	// SaveAs copies the snapshot, and the copy is sealed too
+	out := filepath.Join(t.TempDir(), "copy.bin")
+	if err := reopened.SaveAs(out); err != nil {
+		t.Fatalf("SaveAs: %v", err)
+	}
+	copied := openTestConfig(t, out)
+	if !copied.Finalized() {
+		t.Error("copy lost the finalize marker")
+	}
+	if err := copied.Store("c", "3"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store on the copy = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestSaveAsKeepsOtherReadOnlyReasons(t *testing.T) {
+	p := &mapProvider{keys: map[string][]byte{"": bytes.Repeat([]byte{0x07}, 32)}}
+	c, path := newTestConfig(t, WithKeyProvider(p))
+	mustStore(t, c, map[string]string{"a": "1"})
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	remote, err := NewConfigFromURL(context.Background(), srv.URL+"/config.bin", WithKeyProvider(p), WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("NewConfigFromURL: %v", err)
+	}
+	rollover, err := OpenWithKeys(path, p.keys[""])
+	if err != nil {
+		t.Fatalf("OpenWithKeys: %v", err)
+	}
+	for name, c := range map[string]*Config{"remote": remote, "rollover": rollover} {
+		if !c.Finalized() {
+			t.Errorf("%s: Finalized = false, want true", name)
+		}
+		out := filepath.Join(t.TempDir(), "copy.bin")
+		if err := c.SaveAs(out); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: SaveAs = %v, want ErrReadOnly", name, err)
+		}
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Errorf("%s: SaveAs wrote %s", name, out)
+		}
+	}
+}
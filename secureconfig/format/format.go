@@ -0,0 +1,180 @@
+// Package format encodes and decodes the secureconfig file format. It knows
+// nothing about encryption: keys and values are the opaque strings the
+// secureconfig package stores, so another implementation can read and write
+// files byte for byte compatibly by following this layout.
+//
+// All integers are unsigned 32-bit big endian.
+//
+//	magic      4 bytes, "SCFG"
+//	version    uint32, currently 1
+//	count      uint32, number of entries
+//	entries    count times:
+//	  keyLen   uint32
+//	  key      keyLen bytes
+//	  valueLen uint32
+//	  value    valueLen bytes
+//
+// Keys are unique. Encode writes entries sorted by key, which makes its
+// output canonical; Decode accepts any order. Bytes after the last entry are
+// not part of the file and are left to the caller. Golden vectors are in
+// testdata, described by testdata/vectors.json.
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Magic identifies a secureconfig file
+const Magic = "SCFG"
+
+// Version is the only layout version in use
+const Version = 1
+
+// ErrCorrupted is returned when data does not follow the layout
+var ErrCorrupted = errors.New("config file is corrupted")
+
+// Encode lays out entries in canonical form, sorted by key
+func Encode(entries map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString(Magic)
+	writeUint32(&buf, Version)
+	writeUint32(&buf, uint32(len(keys)))
+	for _, k := range keys {
+		v := entries[k]
+		if uint64(len(k)) > math.MaxUint32 || uint64(len(v)) > math.MaxUint32 {
+			return nil, fmt.Errorf("entry %q is too large to encode", k)
+		}
+		writeUint32(&buf, uint32(len(k)))
+		buf.WriteString(k)
+		writeUint32(&buf, uint32(len(v)))
+		buf.WriteString(v)
+	}
+	return buf.Bytes(), nil
+}
+
+// Span locates a field within the data it was decoded from
+type Span struct {
+	Offset int
+	Length int
+}
+
+// Decode parses data into its entries and returns the number of bytes they
+// took up. A count above maxEntries is rejected before any entry is read.
+// Layout errors wrap ErrCorrupted; an unknown version does not, as the data
+// may be valid for a newer implementation.
+func Decode(data []byte, maxEntries int) (map[string]string, int, error) {
+	var entries map[string]string
+	n, err := walk(data, maxEntries, func(count uint32) {
+		entries = make(map[string]string, count)
+	}, func(key string, value Span) bool {
+		if _, ok := entries[key]; ok {
+			return false
+		}
+		entries[key] = string(data[value.Offset : value.Offset+value.Length])
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, n, nil
+}
+
+// Index validates data like Decode but returns where each value lies in data
+// instead of copying it out, for reading values on demand
+func Index(data []byte, maxEntries int) (map[string]Span, int, error) {
+	var spans map[string]Span
+	n, err := walk(data, maxEntries, func(count uint32) {
+		spans = make(map[string]Span, count)
+	}, func(key string, value Span) bool {
+		if _, ok := spans[key]; ok {
+			return false
+		}
+		spans[key] = value
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return spans, n, nil
+}
+
+// walk checks the header, calls start with the entry count and then entry for
+// each entry in file order. entry returns false for a duplicate key.
+func walk(data []byte, maxEntries int, start func(count uint32), entry func(key string, value Span) bool) (int, error) {
+	if len(data) < 8 {
+		return 0, fmt.Errorf("%w: file too short", ErrCorrupted)
+	}
+	if string(data[:4]) != Magic {
+		return 0, fmt.Errorf("%w: invalid file format", ErrCorrupted)
+	}
+
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version != Version {
+		return 0, fmt.Errorf("unsupported version: %d", version)
+	}
+
+	offset := 8
+	if len(data) < offset+4 {
+		return 0, fmt.Errorf("%w: file too short for entry count", ErrCorrupted)
+	}
+	numEntries := binary.BigEndian.Uint32(data[offset : offset+4])
+	offset += 4
+
+	// Reject absurd counts before looping, every entry needs two lengths
+	if uint64(numEntries) > uint64(maxEntries) {
+		return 0, fmt.Errorf("%w: %d entries exceeds the limit of %d", ErrCorrupted, numEntries, maxEntries)
+	}
+	if uint64(numEntries)*8 > uint64(len(data)-offset) {
+		return 0, fmt.Errorf("%w: file too short for %d entries", ErrCorrupted, numEntries)
+	}
+
+	start(numEntries)
+	for i := uint32(0); i < numEntries; i++ {
+		key, err := readField(data, offset, "key")
+		if err != nil {
+			return 0, err
+		}
+		offset = key.Offset + key.Length
+		value, err := readField(data, offset, "value")
+		if err != nil {
+			return 0, err
+		}
+		offset = value.Offset + value.Length
+
+		if !entry(string(data[key.Offset:key.Offset+key.Length]), value) {
+			return 0, fmt.Errorf("%w: duplicate key", ErrCorrupted)
+		}
+	}
+	return offset, nil
+}
+
+// readField locates the length-prefixed field at offset
+func readField(data []byte, offset int, name string) (Span, error) {
+	if len(data)-offset < 4 {
+		return Span{}, fmt.Errorf("%w: file too short for %s length", ErrCorrupted, name)
+	}
+	n := binary.BigEndian.Uint32(data[offset : offset+4])
+	offset += 4
+
+	if uint64(len(data)-offset) < uint64(n) {
+		return Span{}, fmt.Errorf("%w: file too short for %s data", ErrCorrupted, name)
+	}
+	return Span{Offset: offset, Length: int(n)}, nil
+}
+
+func writeUint32(buf *bytes.Buffer, n uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	buf.Write(b[:])
+}
@@ -0,0 +1,173 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testMaxEntries is the entry limit the vectors are checked against, the
+// secureconfig default
+const testMaxEntries = 1 << 20
+
+// vectors mirrors testdata/vectors.json
+type vectors struct {
+	Valid []struct {
+		Name      string `json:"name"`
+		File      string `json:"file"`
+		Canonical bool   `json:"canonical"`
+		Entries   []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"entries"`
+	} `json:"valid"`
+	Invalid []struct {
+		Name  string `json:"name"`
+		File  string `json:"file"`
+		Error string `json:"error"`
+	} `json:"invalid"`
+}
+
+func loadVectors(t *testing.T) vectors {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v vectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("vectors.json: %v", err)
+	}
+	return v
+}
+
+func readVector(t *testing.T, file string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestValidVectors(t *testing.T) {
+	for _, v := range loadVectors(t).Valid {
+		t.Run(v.Name, func(t *testing.T) {
+			data := readVector(t, v.File)
+			want := make(map[string]string, len(v.Entries))
+			for _, e := range v.Entries {
+				want[e.Key] = e.Value
+			}
+
+			got, n, err := Decode(data, testMaxEntries)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if n != len(data) {
+				t.Errorf("Decode consumed %d of %d bytes", n, len(data))
+			}
+			if len(got) != len(want) {
+				t.Errorf("Decode returned %d entries, want %d", len(got), len(want))
+			}
+			for k, value := range want {
+				if got[k] != value {
+					t.Errorf("entry %q = %q, want %q", k, got[k], value)
+				}
+			}
+
+			spans, _, err := Index(data, testMaxEntries)
+			if err != nil {
+				t.Fatalf("Index: %v", err)
+			}
+			for k, span := range spans {
+				if value := string(data[span.Offset : span.Offset+span.Length]); value != want[k] {
+					t.Errorf("Index locates %q as %q, want %q", k, value, want[k])
+				}
+			}
+
+			encoded, err := Encode(want)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if v.Canonical != bytes.Equal(encoded, data) {
+				if v.Canonical {
+					t.Errorf("Encode does not reproduce the canonical file:\n got %x\nwant %x", encoded, data)
+				} else {
+					t.Error("Encode reproduces a file listed as not canonical")
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidVectors(t *testing.T) {
+	for _, v := range loadVectors(t).Invalid {
+		t.Run(v.Name, func(t *testing.T) {
+			data := readVector(t, v.File)
+			_, _, decodeErr := Decode(data, testMaxEntries)
+			_, _, indexErr := Index(data, testMaxEntries)
+			for name, err := range map[string]error{"Decode": decodeErr, "Index": indexErr} {
+				switch {
+				case err == nil:
+					t.Errorf("%s accepted the file", name)
+				case v.Error == "corrupted" && !errors.Is(err, ErrCorrupted):
+					t.Errorf("%s error = %v, want ErrCorrupted", name, err)
+				case v.Error == "version" && errors.Is(err, ErrCorrupted):
+					t.Errorf("%s error = %v, want a version error rather than ErrCorrupted", name, err)
+				case v.Error != "corrupted" && v.Error != "version":
+					t.Fatalf("unknown error kind %q in vectors.json", v.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestVectorsListEveryFile(t *testing.T) {
+	v := loadVectors(t)
+	listed := make(map[string]bool)
+	for _, valid := range v.Valid {
+		listed[valid.File] = true
+	}
+	for _, invalid := range v.Invalid {
+		listed[invalid.File] = true
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "*.scfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if !listed[filepath.Base(f)] {
+			t.Errorf("%s is not described in vectors.json", f)
+		}
+	}
+	if len(files) != len(listed) {
+		t.Errorf("vectors.json lists %d files, testdata holds %d", len(listed), len(files))
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	entries := map[string]string{"": "", "k": "v", "b": string([]byte{0, 1, 2, 255})}
+	data, err := Encode(entries)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// Trailing bytes are left to the caller
+	got, n, err := Decode(append(data, "trailer"...), testMaxEntries)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if n != len(data) {
+		t.Errorf("Decode consumed %d bytes, want %d", n, len(data))
+	}
+	for k, v := range entries {
+		if got[k] != v {
+			t.Errorf("entry %q = %q, want %q", k, got[k], v)
+		}
+	}
+	if _, _, err := Decode(data, len(entries)-1); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Decode over the entry limit = %v, want ErrCorrupted", err)
+	}
+}
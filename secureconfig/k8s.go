@@ -0,0 +1,107 @@
+package secureconfig
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	// k8sNameRe matches a DNS subdomain as required for object names
+	k8sNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	// k8sNamespaceRe matches a DNS label as required for namespaces
+	k8sNamespaceRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// k8sKeyInvalidRe matches characters not allowed in Secret data keys
+	k8sKeyInvalidRe = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+)
+
+// WithPlaintextExport allows ExportK8sSecret, which writes every value out of
+// the encrypted file. Without it the export returns ErrPlaintextExport, so
+// plaintext never leaves a config that was not opened for the purpose.
+func WithPlaintextExport() Option {
+	return func(c *Config) {
+		c.plainExport = true
+	}
+}
+
+// ExportK8sSecret writes a Kubernetes Secret manifest holding every value,
+// base64 encoded under data. Base64 is not encryption: the output contains
+// every secret in plaintext and must be handled like the secrets themselves,
+// for example sealed or encrypted before it is committed. The config must be
+// opened with WithPlaintextExport.
+//
+// Dotted keys are valid Secret keys and are kept; any other character outside
+// [-._a-zA-Z0-9] is replaced by an underscore. Keys that collide after
+// mapping are an error rather than silently overwriting each other.
+func (c *Config) ExportK8sSecret(w io.Writer, name, namespace string) error {
+	if !c.plainExport {
+		return ErrPlaintextExport
+	}
+	if len(name) > 253 || !k8sNameRe.MatchString(name) {
+		return fmt.Errorf("invalid Secret name: %q", name)
+	}
+	if len(namespace) > 63 || !k8sNamespaceRe.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace: %q", namespace)
+	}
+
+	c.mu.RLock()
+	data, err := c.k8sData()
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(data))
+	for k := range data {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\n  namespace: %s\ntype: Opaque\n",
+		strconv.Quote(name), strconv.Quote(namespace))
+	if len(names) == 0 {
+		fmt.Fprint(bw, "data: {}\n")
+	} else {
+		fmt.Fprint(bw, "data:\n")
+		for _, k := range names {
+			fmt.Fprintf(bw, "  %s: %s\n", strconv.Quote(k), strconv.Quote(data[k]))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write Secret manifest: %v", err)
+	}
+	return nil
+}
+
+// k8sData maps every key to a valid Secret key and its base64 encoded value
+func (c *Config) k8sData() (map[string]string, error) {
+	keys, err := c.listKeys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	data := make(map[string]string, len(keys))
+	origin := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := c.retrieve(key)
+		if err != nil {
+			return nil, err
+		}
+		secretKey := k8sKeyInvalidRe.ReplaceAllString(key, "_")
+		if len(secretKey) > 253 {
+			return nil, fmt.Errorf("key %s is too long for a Secret", key)
+		}
+		if other, ok := origin[secretKey]; ok {
+			return nil, fmt.Errorf("keys %s and %s both map to Secret key %s", other, key, secretKey)
+		}
+		origin[secretKey] = key
+		data[secretKey] = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	return data, nil
+}
@@ -0,0 +1,94 @@
+package secureconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportK8sSecretRequiresOptIn(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"db.password": "hunter2"})
+
+	var buf bytes.Buffer
+	if err := c.ExportK8sSecret(&buf, "app", "default"); !errors.Is(err, ErrPlaintextExport) {
+		t.Errorf("ExportK8sSecret without opt-in = %v, want ErrPlaintextExport", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("ExportK8sSecret wrote %q without opt-in", buf.String())
+	}
+}
+
+func TestExportK8sSecret(t *testing.T) {
+	c, _ := newTestConfig(t, WithPlaintextExport())
+	mustStore(t, c, map[string]string{
+		"db.password":  "hunter2",
+		"api key":      "line one\nline: two",
+		"tls/cert.pem": "",
+	})
+
+	var buf bytes.Buffer
+	if err := c.ExportK8sSecret(&buf, "app-secrets", "prod"); err != nil {
+		t.Fatalf("ExportK8sSecret: %v", err)
+	}
+
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: "app-secrets"
+  namespace: "prod"
+type: Opaque
+data:
+  "api_key": "` + base64.StdEncoding.EncodeToString([]byte("line one\nline: two")) + `"
+  "db.password": "` + base64.StdEncoding.EncodeToString([]byte("hunter2")) + `"
+  "tls_cert.pem": ""
+`
+	if got := buf.String(); got != want {
+		t.Errorf("manifest:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Every data value decodes back to the stored value
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, `  "`) {
+			continue
+		}
+		quoted := line[strings.LastIndex(line, " ")+1:]
+		encoded, err := strconv.Unquote(quoted)
+		if err != nil {
+			t.Fatalf("%s: value is not quoted: %v", line, err)
+		}
+		if _, err := base64.StdEncoding.DecodeString(encoded); err != nil {
+			t.Errorf("%s: value is not base64: %v", line, err)
+		}
+	}
+}
+
+func TestExportK8sSecretEmpty(t *testing.T) {
+	c, _ := newTestConfig(t, WithPlaintextExport())
+	var buf bytes.Buffer
+	if err := c.ExportK8sSecret(&buf, "empty", "default"); err != nil {
+		t.Fatalf("ExportK8sSecret: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "type: Opaque\ndata: {}\n") {
+		t.Errorf("manifest for an empty config:\n%s", buf.String())
+	}
+}
+
+func TestExportK8sSecretRejectsInvalidInput(t *testing.T) {
+	c, _ := newTestConfig(t, WithPlaintextExport())
+	mustStore(t, c, map[string]string{"a/b": "1", "a_b": "2"})
+
+	var buf bytes.Buffer
+	if err := c.ExportK8sSecret(&buf, "app", "default"); err == nil {
+		t.Error("ExportK8sSecret accepted keys that collide after mapping")
+	}
+	if err := c.ExportK8sSecret(&buf, "App_Secrets", "default"); err == nil {
+		t.Error("ExportK8sSecret accepted an invalid name")
+	}
+	if err := c.ExportK8sSecret(&buf, "app", "prod.eu"); err == nil {
+		t.Error("ExportK8sSecret accepted an invalid namespace")
+	}
+}
@@ -0,0 +1,68 @@
+package secureconfig
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// KeyProvider supplies master keys managed outside the config file, such as
+// by a KMS or the OS keyring
+type KeyProvider interface {
+	// Key returns the 32-byte master key for the given key ID
+	Key(id string) ([]byte, error)
+}
+
+// WithKeyProvider fetches the master key from p instead of storing it in the
+// file. On open, p is asked for the key named by the file's key ID; a new
+// file is created with an empty key ID.
+func WithKeyProvider(p KeyProvider) Option {
+	return func(c *Config) {
+		c.keyProvider = p
+	}
+}
+
+// SetKeyID records the identifier of the external key used for this file.
+// The identifier, not the key, is stored so tooling knows which key to fetch.
+// With a KeyProvider the provider must return the current key for id, since
+// the entries stay encrypted under it; use Rekey to move to a different key.
+func (c *Config) SetKeyID(id string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// The key only changes under writeMu, so the provider is asked without
+	// blocking readers
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if c.keyProvider != nil {
+		key, err := c.keyProvider.Key(id)
+		if err != nil {
+			return fmt.Errorf("failed to get key %q from provider: %v", id, err)
+		}
+		if !bytes.Equal(key, c.Key) {
+			return fmt.Errorf("key %q is not the current key, use Rekey to change keys", id)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id == "" {
+		delete(c.DB, keyIDEntry)
+	} else {
+		c.DB[keyIDEntry] = id
+	}
+	return c.writeSecretsFile()
+}
+
+// KeyID returns the identifier of the external key, or "" if none is set
+func (c *Config) KeyID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.keyID()
+}
+
+// keyID is KeyID for callers already holding the lock
+func (c *Config) keyID() string {
+	return c.DB[keyIDEntry]
+}
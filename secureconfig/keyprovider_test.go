@@ -0,0 +1,91 @@
+package secureconfig
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// mapProvider serves keys by ID and records which IDs were asked for
+type mapProvider struct {
+	keys      map[string][]byte
+	requested []string
+}
+
+func (p *mapProvider) Key(id string) ([]byte, error) {
+	p.requested = append(p.requested, id)
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("no key %q", id)
+	}
+	return key, nil
+}
+
+func TestKeyIDPersistedAndPassedToProvider(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	p := &mapProvider{keys: map[string][]byte{"": key, "prod-2026": key}}
+	path := filepath.Join(t.TempDir(), "config.bin")
+
+	c, err := NewConfigWithFile(path, WithKeyProvider(p))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if err := c.SetKeyID("prod-2026"); err != nil {
+		t.Fatalf("SetKeyID: %v", err)
+	}
+	mustStore(t, c, map[string]string{"a": "1"})
+	if _, ok := c.DB[keyEntry]; ok {
+		t.Error("file embeds the key despite the KeyProvider")
+	}
+
+	p2 := &mapProvider{keys: map[string][]byte{"prod-2026": key}}
+	reopened, err := NewConfigWithFile(path, WithKeyProvider(p2))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.KeyID(); got != "prod-2026" {
+		t.Errorf("KeyID = %q, want prod-2026", got)
+	}
+	if len(p2.requested) != 1 || p2.requested[0] != "prod-2026" {
+		t.Errorf("provider asked for %q, want [prod-2026]", p2.requested)
+	}
+	if v, err := reopened.Retrieve("a"); err != nil || v != "1" {
+		t.Errorf("Retrieve = %q, %v", v, err)
+	}
+}
+
+func TestSetKeyIDRejectsADifferentKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	other := bytes.Repeat([]byte{0x43}, 32)
+	p := &mapProvider{keys: map[string][]byte{"": key, "prod-2026": other, "alias": key}}
+	path := filepath.Join(t.TempDir(), "config.bin")
+
+	c, err := NewConfigWithFile(path, WithKeyProvider(p))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	mustStore(t, c, map[string]string{"a": "1"})
+
+	if err := c.SetKeyID("prod-2026"); err == nil {
+		t.Fatal("SetKeyID accepted an ID naming a different key")
+	}
+	if err := c.SetKeyID("missing"); err == nil {
+		t.Error("SetKeyID accepted an ID the provider does not know")
+	}
+	if got := c.KeyID(); got != "" {
+		t.Errorf("KeyID = %q after rejected changes, want it unchanged", got)
+	}
+
+	// The file still opens with the key its entries are encrypted under
+	if err := c.SetKeyID("alias"); err != nil {
+		t.Fatalf("SetKeyID with the same key: %v", err)
+	}
+	reopened, err := NewConfigWithFile(path, WithKeyProvider(p))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if v, err := reopened.Retrieve("a"); err != nil || v != "1" {
+		t.Errorf("Retrieve = %q, %v", v, err)
+	}
+}
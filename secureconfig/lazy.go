@@ -0,0 +1,55 @@
+package secureconfig
+
+import "github.com/ddelpero/secureconfig/format"
+
+// WithLazyLoad makes opening a file index where each value lies in the file
+// contents instead of copying all values out, cutting startup time and
+// memory for very large files. A value is read from the retained contents
+// when it is needed. The first write loads every value and drops the
+// retained contents. Until then Config.DB holds empty placeholders for values
+// that have not been loaded.
+func WithLazyLoad() Option {
+	return func(c *Config) {
+		c.lazy = true
+	}
+}
+
+// parseLazy indexes data into c.DB, copying out only the reserved entries
+func (c *Config) parseLazy(data []byte) (int, error) {
+	spans, n, err := format.Index(data, c.maxEntries)
+	if err != nil {
+		return 0, err
+	}
+	c.DB = make(map[string]string, len(spans))
+	for k, span := range spans {
+		if isReserved(k) {
+			c.DB[k] = string(data[span.Offset : span.Offset+span.Length])
+			delete(spans, k)
+			continue
+		}
+		c.DB[k] = ""
+	}
+	c.raw, c.spans = data, spans
+	return n, nil
+}
+
+// stored returns the encoded value of DB entry k, reading it from the
+// retained file contents when it has not been loaded
+func (c *Config) stored(k string) string {
+	if span, ok := c.spans[k]; ok {
+		return string(c.raw[span.Offset : span.Offset+span.Length])
+	}
+	return c.DB[k]
+}
+
+// loaded returns the DB with every value in place, for writing
+func (c *Config) loaded() map[string]string {
+	if c.spans == nil {
+		return c.DB
+	}
+	db := make(map[string]string, len(c.DB))
+	for k := range c.DB {
+		db[k] = c.stored(k)
+	}
+	return db
+}
@@ -0,0 +1,79 @@
+package secureconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// largeConfig writes a file of n entries with 1 KiB values for the open
+// benchmarks and returns its path
+func largeConfig(b *testing.B, n int) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "config.bin")
+	c, err := NewConfigWithFile(path)
+	if err != nil {
+		b.Fatalf("NewConfigWithFile: %v", err)
+	}
+	pairs := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		pairs[fmt.Sprintf("key.%d", i)] = strings.Repeat("v", 1024)
+	}
+	if err := c.StoreAll(pairs); err != nil {
+		b.Fatalf("StoreAll: %v", err)
+	}
+	return path
+}
+
+func benchmarkOpen(b *testing.B, opts ...Option) {
+	path := largeConfig(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewConfigWithFile(path, opts...); err != nil {
+			b.Fatalf("NewConfigWithFile: %v", err)
+		}
+	}
+}
+
+func BenchmarkOpenLazy(b *testing.B) {
+	benchmarkOpen(b, WithLazyLoad())
+}
+
+func BenchmarkOpenEager(b *testing.B) {
+	benchmarkOpen(b)
+}
+
+func TestLazyLoad(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "1", "b": "2", "c": "3"})
+
+	lazy, err := NewConfigWithFile(path, WithLazyLoad())
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for k, v := range lazy.DB {
+		if !isReserved(k) && v != "" {
+			t.Errorf("value of %s was copied out on a lazy open", k)
+		}
+	}
+	if got, err := lazy.Retrieve("b"); err != nil || got != "2" {
+		t.Errorf("Retrieve(b) = %q, %v; want 2", got, err)
+	}
+
+	// The first write loads every value and drops the retained contents
+	mustStore(t, lazy, map[string]string{"d": "4"})
+	if lazy.raw != nil || lazy.spans != nil {
+		t.Error("file contents retained after a write")
+	}
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for k, want := range map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"} {
+		if got, err := reopened.Retrieve(k); err != nil || got != want {
+			t.Errorf("after a lazy write: Retrieve(%q) = %q, %v; want %q", k, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package secureconfig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pseudonymLen is the number of HMAC bytes kept per key segment
+const pseudonymLen = 12
+
+// ManifestVersion is the version of the manifest document layout
+const ManifestVersion = 1
+
+// Rotation statuses reported in a manifest, see WithRotationPeriod
+const (
+	RotationCurrent = "current"
+	RotationDue     = "due"
+)
+
+// manifest is the metadata-only document written by ExportManifest
+type manifest struct {
+	Version       int             `json:"version"`
+	Generated     time.Time       `json:"generated"`
+	Pseudonymized bool            `json:"pseudonymized,omitempty"`
+	Entries       []manifestEntry `json:"entries"`
+}
+
+// manifestEntry describes one stored entry without its value
+type manifestEntry struct {
+	Key      string     `json:"key"`
+	Size     int        `json:"size"`
+	Created  *time.Time `json:"created,omitempty"`
+	Updated  *time.Time `json:"updated,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	Rotation string     `json:"rotation,omitempty"`
+}
+
+// WithRotationPeriod makes manifests report a rotation status for every
+// entry: RotationDue once it was last updated more than d ago, and
+// RotationCurrent otherwise. Without it the status is omitted.
+func WithRotationPeriod(d time.Duration) Option {
+	return func(c *Config) {
+		if d <= 0 {
+			c.setOptErr(fmt.Errorf("rotation period must be positive, got %v", d))
+			return
+		}
+		c.rotateAfter = d
+	}
+}
+
+// SetTags replaces the inventory labels of a stored key. Tags are kept with
+// the entry's metadata and listed in manifests; calling SetTags with no tags
+// clears them.
+func (c *Config) SetTags(key string, tags ...string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if _, ok := c.findEntry(key); !ok || c.softDeleted(key) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	meta, ok := c.meta[key]
+	if !ok {
+		meta = &entryMeta{} // entry written before metadata was tracked
+		c.meta[key] = meta
+	}
+	old := meta.Tags
+	meta.Tags = nil
+	if len(tags) > 0 {
+		meta.Tags = append([]string(nil), tags...)
+		sort.Strings(meta.Tags)
+	}
+	if err := c.writeSecretsFile(); err != nil {
+		meta.Tags = old
+		return err
+	}
+	return nil
+}
+
+// ExportManifest writes a JSON inventory of the stored entries to w.
+// Each entry lists the key name, the plaintext size in bytes, the
+// created/updated times when known, any tags set with SetTags and, with
+// WithRotationPeriod, the rotation status. Values are never decrypted, so
+// the manifest is safe to commit or hand to asset-management tools.
+func (c *Config) ExportManifest(w io.Writer) error {
+	c.mu.RLock()
+	entries, err := c.manifestEntries()
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return c.writeManifest(w, manifest{Entries: entries})
+}
+
+// ExportPseudonymized writes the same manifest as ExportManifest with every
+// key name replaced by a pseudonym, for sharing change manifests without
+// revealing key names. Each dotted segment is replaced by a truncated
+// HMAC-SHA256 under salt, so the hierarchy is preserved and the same key
+// always maps to the same pseudonym for a given salt, but differs across
+// salts.
+func (c *Config) ExportPseudonymized(w io.Writer, salt []byte) error {
+	if len(salt) == 0 {
+		return errors.New("salt must not be empty")
+	}
+
+	c.mu.RLock()
+	entries, err := c.manifestEntries()
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].Key = pseudonymize(entries[i].Key, salt)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return c.writeManifest(w, manifest{Pseudonymized: true, Entries: entries})
+}
+
+// writeManifest stamps and encodes a manifest as indented JSON
+func (c *Config) writeManifest(w io.Writer, m manifest) error {
+	m.Version = ManifestVersion
+	m.Generated = c.now()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// pseudonymize replaces each dotted segment of key with its keyed hash
+func pseudonymize(key string, salt []byte) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(part))
+		parts[i] = hex.EncodeToString(mac.Sum(nil)[:pseudonymLen])
+	}
+	return strings.Join(parts, ".")
+}
+
+// manifestEntries collects the manifest entries sorted by key
+func (c *Config) manifestEntries() ([]manifestEntry, error) {
+	entries := []manifestEntry{}
+	now := c.now()
+	c.eachKey(func(k, decKey string) bool {
+		valueBytes, err := base64.StdEncoding.DecodeString(c.stored(k))
+		if err != nil {
+			return true // Skip invalid entries
+		}
+
+		entry := manifestEntry{
+			Key: decKey,
+			// Size is derived from the ciphertext so the value stays sealed
+			Size: len(valueBytes) - c.GCM.NonceSize() - c.GCM.Overhead(),
+		}
+		if meta, ok := c.meta[decKey]; ok {
+			if meta.Deleted {
+				return true
+			}
+			if !meta.Created.IsZero() {
+				created, updated := meta.Created, meta.Updated
+				entry.Created = &created
+				entry.Updated = &updated
+				entry.Rotation = c.rotationStatus(updated, now)
+			}
+			entry.Tags = meta.Tags
+		}
+		entries = append(entries, entry)
+		return true
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// rotationStatus reports whether an entry last updated at updated is due
+// for rotation, or "" when no rotation period is configured
+func (c *Config) rotationStatus(updated, now time.Time) string {
+	if c.rotateAfter == 0 {
+		return ""
+	}
+	if now.Sub(updated) > c.rotateAfter {
+		return RotationDue
+	}
+	return RotationCurrent
+}
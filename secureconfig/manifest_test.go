@@ -0,0 +1,161 @@
+package secureconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportManifest(t *testing.T) {
+	c, _ := newTestConfig(t)
+	pairs := map[string]string{
+		"db.password": "hunter2-plaintext",
+		"api.token":   "tok-0123456789",
+		"empty":       "",
+	}
+	mustStore(t, c, pairs)
+
+	var buf bytes.Buffer
+	if err := c.ExportManifest(&buf); err != nil {
+		t.Fatalf("ExportManifest: %v", err)
+	}
+	for _, v := range pairs {
+		if v != "" && strings.Contains(buf.String(), v) {
+			t.Errorf("manifest contains plaintext value %q", v)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	if m.Version != ManifestVersion {
+		t.Errorf("version = %d, want %d", m.Version, ManifestVersion)
+	}
+	if len(m.Entries) != len(pairs) {
+		t.Fatalf("got %d entries, want %d", len(m.Entries), len(pairs))
+	}
+	for _, e := range m.Entries {
+		v, ok := pairs[e.Key]
+		if !ok {
+			t.Errorf("unexpected key %q", e.Key)
+			continue
+		}
+		if e.Size != len(v) {
+			t.Errorf("%s: size = %d, want %d", e.Key, e.Size, len(v))
+		}
+		if e.Created == nil || e.Updated == nil {
+			t.Errorf("%s: missing timestamps", e.Key)
+		}
+	}
+}
+
+func TestExportManifestTagsAndRotation(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}
+	c, path := newTestConfig(t, WithClock(clock.now), WithRotationPeriod(30*24*time.Hour))
+	mustStore(t, c, map[string]string{"old": "1"})
+	clock.t = clock.t.Add(60 * 24 * time.Hour)
+	mustStore(t, c, map[string]string{"fresh": "2"})
+	if err := c.SetTags("old", "team:payments", "pci"); err != nil {
+		t.Fatalf("SetTags: %v", err)
+	}
+	if err := c.SetTags("missing", "x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetTags on a missing key = %v, want ErrNotFound", err)
+	}
+
+	// Tags are stored with the file
+	c, err := NewConfigWithFile(path, WithClock(clock.now), WithRotationPeriod(30*24*time.Hour))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := c.ExportManifest(&buf); err != nil {
+		t.Fatalf("ExportManifest: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	want := map[string]struct {
+		tags     string
+		rotation string
+	}{
+		"old":   {"pci,team:payments", RotationDue},
+		"fresh": {"", RotationCurrent},
+	}
+	for _, e := range m.Entries {
+		w := want[e.Key]
+		if got := strings.Join(e.Tags, ","); got != w.tags {
+			t.Errorf("%s: tags = %q, want %q", e.Key, got, w.tags)
+		}
+		if e.Rotation != w.rotation {
+			t.Errorf("%s: rotation = %q, want %q", e.Key, e.Rotation, w.rotation)
+		}
+	}
+
+	// Without a rotation period the status is left out
+	c, err = NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	buf.Reset()
+	if err := c.ExportManifest(&buf); err != nil {
+		t.Fatalf("ExportManifest: %v", err)
+	}
+	if strings.Contains(buf.String(), `"rotation"`) {
+		t.Errorf("manifest reports rotation without a period:\n%s", buf.String())
+	}
+}
+
+func TestExportPseudonymized(t *testing.T) {
+	c, _ := newTestConfig(t)
+	keys := []string{"api.token", "db.password", "db.user"}
+	mustStore(t, c, map[string]string{"db.password": "x", "db.user": "y", "api.token": "z"})
+
+	export := func(salt string) map[string]bool {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := c.ExportPseudonymized(&buf, []byte(salt)); err != nil {
+			t.Fatalf("ExportPseudonymized: %v", err)
+		}
+		var m manifest
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatalf("manifest is not JSON: %v", err)
+		}
+		if !m.Pseudonymized {
+			t.Error("manifest is not marked pseudonymized")
+		}
+		pseudonyms := make(map[string]bool)
+		for _, e := range m.Entries {
+			pseudonyms[e.Key] = true
+		}
+		return pseudonyms
+	}
+
+	first, again, other := export("salt-a"), export("salt-a"), export("salt-b")
+	if len(first) != len(keys) {
+		t.Fatalf("got %d distinct pseudonyms, want %d", len(first), len(keys))
+	}
+	for _, key := range keys {
+		p := pseudonymize(key, []byte("salt-a"))
+		if !first[p] || !again[p] {
+			t.Errorf("%s is not exported as %s under the same salt every time", key, p)
+		}
+		if other[p] {
+			t.Errorf("%s has the same pseudonym under a different salt", key)
+		}
+		if p == key || strings.Count(p, ".") != strings.Count(key, ".") {
+			t.Errorf("pseudonym %s of %s does not hide the name while keeping its segments", p, key)
+		}
+	}
+
+	// The hierarchy survives: both db keys share their first segment
+	password := pseudonymize("db.password", []byte("salt-a"))
+	user := pseudonymize("db.user", []byte("salt-a"))
+	if strings.Split(password, ".")[0] != strings.Split(user, ".")[0] {
+		t.Errorf("db.password and db.user map to %s and %s, want a shared first segment", password, user)
+	}
+}
@@ -0,0 +1,70 @@
+package secureconfig
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CipherName names the cipher entries are encrypted with
+const CipherName = "AES-256-GCM"
+
+// MigrationPlan describes what re-encrypting a config would involve
+type MigrationPlan struct {
+	SourceVersion int
+	TargetVersion int
+	SourceCipher  string
+	TargetCipher  string
+
+	// Entries is the number of stored entries that would be re-encrypted,
+	// including soft deleted ones
+	Entries int
+
+	// EstimatedDuration is how long re-encrypting the entries took in the
+	// dry run, writing the file comes on top
+	EstimatedDuration time.Duration
+}
+
+// PlanMigration reports the impact of rewriting the config with Rekey without
+// modifying anything. Every entry is decrypted and re-encrypted under a
+// throwaway key and the results are discarded, so an entry that would make
+// Rekey fail is reported here as an error.
+func (c *Config) PlanMigration() (MigrationPlan, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	plan := MigrationPlan{
+		SourceVersion: Version,
+		TargetVersion: Version,
+		SourceCipher:  CipherName,
+		TargetCipher:  CipherName,
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return MigrationPlan{}, fmt.Errorf("failed to generate key: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	scratch := &Config{GCM: gcm}
+
+	start := time.Now()
+	for k := range c.DB {
+		if isReserved(k) {
+			continue
+		}
+		key, value, err := c.decryptPair(k, c.stored(k))
+		if err != nil {
+			return MigrationPlan{}, err
+		}
+		if _, _, err := scratch.encryptPair(key, value); err != nil {
+			return MigrationPlan{}, err
+		}
+		plan.Entries++
+	}
+	plan.EstimatedDuration = time.Since(start)
+	return plan, nil
+}
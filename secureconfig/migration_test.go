@@ -0,0 +1,58 @@
+package secureconfig
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestPlanMigration(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "1", "b": "2", "c": "3"})
+	if err := c.SoftDelete("c"); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := c.PlanMigration()
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if plan.SourceVersion != Version || plan.TargetVersion != Version {
+		t.Errorf("versions = %d -> %d, want %d -> %d", plan.SourceVersion, plan.TargetVersion, Version, Version)
+	}
+	if plan.SourceCipher != CipherName || plan.TargetCipher != CipherName {
+		t.Errorf("ciphers = %s -> %s, want %s", plan.SourceCipher, plan.TargetCipher, CipherName)
+	}
+	// Soft deleted entries are re-encrypted too
+	if plan.Entries != 3 {
+		t.Errorf("Entries = %d, want 3", plan.Entries)
+	}
+	if plan.EstimatedDuration < 0 {
+		t.Errorf("EstimatedDuration = %v, want a duration", plan.EstimatedDuration)
+	}
+
+	// The dry run changes nothing
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("PlanMigration modified the file")
+	}
+	if got, err := c.Retrieve("a"); err != nil || got != "1" {
+		t.Errorf("Retrieve(a) after PlanMigration = %q, %v; want 1", got, err)
+	}
+}
+
+func TestPlanMigrationReportsUndecryptableEntries(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "1"})
+	for k := range c.DB {
+		if !isReserved(k) {
+			c.DB[k] = "bm90IHNlYWxlZA==" // base64 of data that is not sealed
+		}
+	}
+	if _, err := c.PlanMigration(); err == nil {
+		t.Error("PlanMigration accepted an entry Rekey would fail on")
+	}
+}
@@ -0,0 +1,60 @@
+package secureconfig
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Option configures optional behaviour of a Config
+type Option func(*Config)
+
+// WithLogger sets the logger used for warnings
+func WithLogger(l *log.Logger) Option {
+	return func(c *Config) {
+		c.logger = l
+	}
+}
+
+// WithClock replaces the wall clock used for entry timestamps
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.clock = now
+	}
+}
+
+// WithClockSkewCheck guards entry timestamps against a clock that moves
+// backward. The newest stored timestamp is used as a baseline: when the
+// clock reads earlier, a warning is logged and the baseline is used instead,
+// so timestamps never go back in time.
+func WithClockSkewCheck() Option {
+	return func(c *Config) {
+		c.skewCheck = true
+	}
+}
+
+// WithMaxEntries limits how many entries a file may declare before it is
+// rejected as corrupted, see DefaultMaxEntries. n must be positive, opening
+// fails otherwise.
+func WithMaxEntries(n int) Option {
+	return func(c *Config) {
+		if n <= 0 {
+			c.setOptErr(fmt.Errorf("max entries must be positive, got %d", n))
+			return
+		}
+		c.maxEntries = n
+	}
+}
+
+// setOptErr records the first invalid option for the constructor to return
+func (c *Config) setOptErr(err error) {
+	if c.optErr == nil {
+		c.optErr = err
+	}
+}
+
+// defaultLogger is used when no logger is configured
+func defaultLogger() *log.Logger {
+	return log.New(os.Stderr, "secureconfig: ", log.LstdFlags)
+}
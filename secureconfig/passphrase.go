@@ -0,0 +1,220 @@
+package secureconfig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// TokenVersion is the layout version of tokens made by EncryptString
+const TokenVersion = 1
+
+// Argon2id parameters used by EncryptString
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024 // KiB
+	kdfThreads = 4
+	kdfKeyLen  = 32
+	kdfSaltLen = 16
+
+	// maxTokenMemory bounds the memory a token may demand when decrypting,
+	// whatever maximum is configured
+	maxTokenMemory = 4 * 1024 * 1024 // KiB
+
+	// minKDFMemory is the smallest memory Argon2id accepts per thread
+	minKDFMemory = 8 // KiB
+	// maxKDFTime bounds the extra passes added to make up for clamped memory
+	maxKDFTime = 4 * kdfTime
+)
+
+// TokenOption configures a single EncryptString or DecryptString call
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	maxMemory uint32 // KiB, 0 for the default
+	logger    *log.Logger
+}
+
+// WithTokenMaxMemory sets the Argon2id memory maximum, in KiB, that
+// EncryptString uses and DecryptString accepts. Zero keeps the default,
+// under which EncryptString uses 64 MiB and DecryptString accepts no more.
+// Lower it for devices where 64 MiB exceeds the available RAM, or raise it to
+// decrypt tokens made elsewhere with more memory.
+//
+// When the maximum is below the default, EncryptString clamps memory (and
+// parallelism if needed) and adds passes to partly make up for it, logging a
+// warning because the derived key is weaker. The parameters actually used
+// are recorded in the token, so DecryptString always derives the same key.
+// DecryptString refuses tokens that need more memory than the maximum, or
+// more passes than EncryptString ever uses, before deriving anything, so a
+// crafted token cannot exhaust the device.
+func WithTokenMaxMemory(kib uint32) TokenOption {
+	return func(o *tokenOptions) {
+		o.maxMemory = kib
+	}
+}
+
+// WithTokenLogger sets the logger EncryptString warns on when it clamps the
+// Argon2id parameters
+func WithTokenLogger(l *log.Logger) TokenOption {
+	return func(o *tokenOptions) {
+		o.logger = l
+	}
+}
+
+// newTokenOptions applies opts over the defaults
+func newTokenOptions(opts []TokenOption) tokenOptions {
+	var o tokenOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = defaultLogger()
+	}
+	return o
+}
+
+// tokenHeaderLen is version, time, memory and threads ahead of the salt
+const tokenHeaderLen = 1 + 4 + 4 + 1
+
+// kdfParams are the Argon2id settings recorded in a token
+type kdfParams struct {
+	time    uint32
+	memory  uint32 // KiB
+	threads uint8
+}
+
+// EncryptString encrypts plaintext with a key derived from passphrase and
+// returns a self-describing base64 token, without any config file.
+//
+// The decoded token is laid out as:
+//
+//	version  1 byte
+//	time     4 bytes, Argon2id iterations
+//	memory   4 bytes, Argon2id memory in KiB
+//	threads  1 byte, Argon2id parallelism
+//	salt     16 bytes
+//	nonce    12 bytes
+//	sealed   AES-256-GCM ciphertext and tag
+//
+// Integers are big endian, and the bytes ahead of the nonce are
+// authenticated as additional data.
+func EncryptString(passphrase, plaintext string, opts ...TokenOption) (string, error) {
+	params, err := encryptParams(newTokenOptions(opts))
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, kdfSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(TokenVersion)
+	binary.Write(&buf, binary.BigEndian, params.time)
+	binary.Write(&buf, binary.BigEndian, params.memory)
+	buf.WriteByte(params.threads)
+	buf.Write(salt)
+	header := buf.Bytes()
+
+	gcm, err := newGCM(deriveKey(passphrase, salt, params))
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	token := append(header, nonce...)
+	token = gcm.Seal(token, nonce, []byte(plaintext), header)
+	return base64.StdEncoding.EncodeToString(token), nil
+}
+
+// DecryptString decrypts a token made by EncryptString
+func DecryptString(passphrase, token string, opts ...TokenOption) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode token: %v", err)
+	}
+	if len(data) < tokenHeaderLen+kdfSaltLen {
+		return "", fmt.Errorf("token too short")
+	}
+	if data[0] != TokenVersion {
+		return "", fmt.Errorf("unsupported token version: %d", data[0])
+	}
+
+	params := kdfParams{
+		time:    binary.BigEndian.Uint32(data[1:5]),
+		memory:  binary.BigEndian.Uint32(data[5:9]),
+		threads: data[9],
+	}
+	if params.time == 0 || params.time > maxKDFTime || params.memory == 0 || params.threads == 0 || params.memory > maxTokenMemory {
+		return "", fmt.Errorf("invalid key derivation parameters in token")
+	}
+	limit := newTokenOptions(opts).maxMemory
+	if limit == 0 {
+		limit = kdfMemory
+	}
+	if params.memory > limit {
+		return "", fmt.Errorf("token needs %d KiB of Argon2id memory, above the maximum of %d KiB", params.memory, limit)
+	}
+
+	headerLen := tokenHeaderLen + kdfSaltLen
+	header, salt := data[:headerLen], data[tokenHeaderLen:headerLen]
+
+	gcm, err := newGCM(deriveKey(passphrase, salt, params))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < headerLen+gcm.NonceSize() {
+		return "", fmt.Errorf("token too short")
+	}
+	nonce, sealed := data[headerLen:headerLen+gcm.NonceSize()], data[headerLen+gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptParams returns the default Argon2id parameters clamped to the
+// configured memory maximum
+func encryptParams(o tokenOptions) (kdfParams, error) {
+	params := kdfParams{time: kdfTime, memory: kdfMemory, threads: kdfThreads}
+	limit := o.maxMemory
+	if limit == 0 || limit >= params.memory {
+		return params, nil
+	}
+	if limit < minKDFMemory {
+		return kdfParams{}, fmt.Errorf("maximum KDF memory of %d KiB is below the Argon2id minimum of %d KiB", limit, minKDFMemory)
+	}
+
+	// Argon2id needs 8 KiB per thread
+	for params.threads > 1 && limit < minKDFMemory*uint32(params.threads) {
+		params.threads--
+	}
+	// Trade the lost memory for extra passes, within reason
+	passes := params.time * ((params.memory + limit - 1) / limit)
+	if passes > maxKDFTime {
+		passes = maxKDFTime
+	}
+	params.memory, params.time = limit, passes
+
+	o.logger.Printf("warning: Argon2id memory clamped from %d KiB to %d KiB (%d passes, %d threads), key derivation is weaker",
+		kdfMemory, params.memory, params.time, params.threads)
+	return params, nil
+}
+
+// deriveKey stretches passphrase into an AES-256 key with Argon2id
+func deriveKey(passphrase string, salt []byte, p kdfParams) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, p.time, p.memory, p.threads, kdfKeyLen)
+}
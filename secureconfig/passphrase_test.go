@@ -0,0 +1,178 @@
+package secureconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestEncryptStringRoundTrip(t *testing.T) {
+	for _, plaintext := range []string{"", "s3cret", strings.Repeat("x", 4096)} {
+		token, err := EncryptString("passphrase", plaintext)
+		if err != nil {
+			t.Fatalf("EncryptString: %v", err)
+		}
+		got, err := DecryptString("passphrase", token)
+		if err != nil || got != plaintext {
+			t.Errorf("DecryptString = %q, %v; want %q", got, err, plaintext)
+		}
+	}
+
+	token, err := EncryptString("passphrase", "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	if _, err := DecryptString("wrong", token); err == nil {
+		t.Error("DecryptString accepted a wrong passphrase")
+	}
+}
+
+func TestDecryptStringTamperedToken(t *testing.T) {
+	token, err := EncryptString("passphrase", "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(token)
+
+	regions := map[string]int{
+		"salt":       tokenHeaderLen,
+		"nonce":      tokenHeaderLen + kdfSaltLen,
+		"ciphertext": len(data) - 1,
+	}
+	for name, offset := range regions {
+		tampered := append([]byte(nil), data...)
+		tampered[offset] ^= 0x01
+		if _, err := DecryptString("passphrase", base64.StdEncoding.EncodeToString(tampered)); err == nil {
+			t.Errorf("DecryptString accepted a token with a tampered %s", name)
+		}
+	}
+
+	if _, err := DecryptString("passphrase", base64.StdEncoding.EncodeToString(data[:tokenHeaderLen+kdfSaltLen+4])); err == nil {
+		t.Error("DecryptString accepted a truncated token")
+	}
+	version := append([]byte(nil), data...)
+	version[0] = TokenVersion + 1
+	if _, err := DecryptString("passphrase", base64.StdEncoding.EncodeToString(version)); err == nil {
+		t.Error("DecryptString accepted an unknown token version")
+	}
+}
+
+func TestDecryptStringRejectsExpensiveParameters(t *testing.T) {
+	token, err := EncryptString("passphrase", "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	tests := []struct {
+		name    string
+		params  kdfParams
+		wantErr string
+	}{
+		{"too many passes", kdfParams{time: maxKDFTime + 1, memory: kdfMemory, threads: kdfThreads}, "invalid key derivation parameters"},
+		{"zero passes", kdfParams{time: 0, memory: kdfMemory, threads: kdfThreads}, "invalid key derivation parameters"},
+		{"over the hard limit", kdfParams{time: kdfTime, memory: maxTokenMemory + 1, threads: kdfThreads}, "invalid key derivation parameters"},
+		{"over the default", kdfParams{time: kdfTime, memory: kdfMemory + 1, threads: kdfThreads}, "above the maximum of 65536 KiB"},
+	}
+	for _, tt := range tests {
+		_, err := DecryptString("passphrase", withParams(t, token, tt.params))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: DecryptString error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Raising the maximum lets the parameters through to key derivation,
+	// which then fails only because the forged header is authenticated
+	raised := withParams(t, token, kdfParams{time: 1, memory: kdfMemory + minKDFMemory, threads: kdfThreads})
+	_, err = DecryptString("passphrase", raised, WithTokenMaxMemory(kdfMemory+minKDFMemory))
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("DecryptString error = %v with a raised maximum, want a decryption failure", err)
+	}
+}
+
+func TestEncryptStringClampsMemory(t *testing.T) {
+	var logs bytes.Buffer
+	const limit = 16 * 1024 // KiB
+	token, err := EncryptString("passphrase", "s3cret", WithTokenMaxMemory(limit), WithTokenLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+
+	p := tokenParams(t, token)
+	if p.memory != limit || p.time <= kdfTime || p.time > maxKDFTime {
+		t.Errorf("clamped parameters = %+v, want %d KiB and between %d and %d passes", p, limit, kdfTime+1, maxKDFTime)
+	}
+	if !strings.Contains(logs.String(), "clamped from 65536 KiB to 16384 KiB") {
+		t.Errorf("clamp warning not logged, logs: %q", logs.String())
+	}
+
+	// The token records what was used, so it decrypts under the same
+	// maximum and under the default
+	for _, opts := range [][]TokenOption{{WithTokenMaxMemory(limit)}, nil} {
+		if got, err := DecryptString("passphrase", token, opts...); err != nil || got != "s3cret" {
+			t.Errorf("DecryptString = %q, %v; want s3cret", got, err)
+		}
+	}
+
+	// A maximum at or above the default leaves the parameters alone
+	logs.Reset()
+	token, err = EncryptString("passphrase", "s3cret", WithTokenMaxMemory(kdfMemory), WithTokenLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	if p := tokenParams(t, token); p != (kdfParams{time: kdfTime, memory: kdfMemory, threads: kdfThreads}) {
+		t.Errorf("parameters = %+v, want the defaults", p)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("unexpected warning: %q", logs.String())
+	}
+}
+
+func TestEncryptStringClampsThreads(t *testing.T) {
+	var logs bytes.Buffer
+	token, err := EncryptString("passphrase", "s3cret", WithTokenMaxMemory(2*minKDFMemory), WithTokenLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	if p := tokenParams(t, token); p.memory != 2*minKDFMemory || p.threads != 2 || p.time != maxKDFTime {
+		t.Errorf("parameters = %+v, want 16 KiB, 2 threads and %d passes", p, maxKDFTime)
+	}
+	if got, err := DecryptString("passphrase", token); err != nil || got != "s3cret" {
+		t.Errorf("DecryptString = %q, %v; want s3cret", got, err)
+	}
+	if logs.Len() == 0 {
+		t.Error("clamp warning not logged")
+	}
+
+	if _, err := EncryptString("passphrase", "s3cret", WithTokenMaxMemory(minKDFMemory-1)); err == nil {
+		t.Error("EncryptString accepted a maximum below the Argon2id minimum")
+	}
+}
+
+// tokenParams reads the Argon2id parameters from a token's header
+func tokenParams(t *testing.T, token string) kdfParams {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kdfParams{
+		time:    binary.BigEndian.Uint32(data[1:5]),
+		memory:  binary.BigEndian.Uint32(data[5:9]),
+		threads: data[9],
+	}
+}
+
+// withParams rewrites the Argon2id parameters in a token's header
+func withParams(t *testing.T, token string, p kdfParams) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(data[1:5], p.time)
+	binary.BigEndian.PutUint32(data[5:9], p.memory)
+	data[9] = p.threads
+	return base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,46 @@
+package secureconfig
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// filePerm is the only mode a config file should have
+const filePerm os.FileMode = 0600
+
+// WithStrictPermissions logs a warning when a write replaces a file whose
+// mode had been loosened from 0600, for example by an external chmod in a
+// shared environment. Every write produces a 0600 file with or without this
+// option, so it only reports the drift, and checks the new file in case the
+// filesystem did not keep the mode.
+func WithStrictPermissions() Option {
+	return func(c *Config) {
+		c.strictPerms = true
+	}
+}
+
+// enforcePermissions makes sure filename is 0600. prevMode is the mode of the
+// file that was replaced, or zero when there was none.
+func (c *Config) enforcePermissions(filename string, prevMode os.FileMode) error {
+	// Unix permission bits are not meaningful on Windows
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	if prevMode != 0 && prevMode != filePerm {
+		c.logger.Printf("warning: %s had mode %v, tightened to %v", filename, prevMode, filePerm)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("failed to check config file permissions: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != filePerm {
+		if err := os.Chmod(filename, filePerm); err != nil {
+			return fmt.Errorf("failed to tighten config file permissions: %v", err)
+		}
+		c.logger.Printf("warning: %s had mode %v after write, tightened to %v", filename, mode, filePerm)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package secureconfig
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStrictPermissions(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		var logs bytes.Buffer
+		opts := []Option{WithLogger(log.New(&logs, "", 0))}
+		if strict {
+			opts = append(opts, WithStrictPermissions())
+		}
+		c, path := newTestConfig(t, opts...)
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mustStore(t, c, map[string]string{"k": "v"})
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != filePerm {
+			t.Errorf("strict=%v: mode after write = %v, want %v", strict, mode, filePerm)
+		}
+		warned := strings.Contains(logs.String(), "had mode -rw-r--r--")
+		if warned != strict {
+			t.Errorf("strict=%v: warned = %v, logs: %q", strict, warned, logs.String())
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package secureconfig
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// minSealedField is the shortest base64 field that can hold a sealed string:
+// a nonce and tag around an empty plaintext
+const minSealedField = (12 + 16 + 2) / 3 * 4
+
+// RegionError is a range of bytes RecoverFromBytes could not salvage. Key is
+// set when an entry's key name decrypted but its value did not.
+type RegionError struct {
+	Offset int
+	Length int
+	Key    string
+}
+
+func (e *RegionError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("bytes %d to %d: value of %s could not be recovered", e.Offset, e.Offset+e.Length, e.Key)
+	}
+	return fmt.Sprintf("bytes %d to %d could not be recovered", e.Offset, e.Offset+e.Length)
+}
+
+// RecoverFromBytes is a last resort for a file too damaged to open. It scans
+// data at every offset for length-prefixed fields that decrypt under key and
+// rebuilds a clean config from every entry whose key name and value both
+// survive, with metadata and the key ID where those survive too. A file
+// sealed with Finalize stays sealed: the result is read-only. The result has
+// no file; write it with SaveAs.
+//
+// The returned errors describe what was lost, mostly as *RegionError for
+// ranges of data that yielded nothing. The config is nil only if key is
+// unusable.
+func RecoverFromBytes(data []byte, key []byte) (*Config, []error) {
+	if len(key) != 32 {
+		return nil, []error{fmt.Errorf("key must be 32 bytes, got %d", len(key))}
+	}
+	plain, _, err := newCiphers(key, false)
+	if err != nil {
+		return nil, []error{err}
+	}
+	kek, vek, err := newCiphers(key, true)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	s := &scan{data: data}
+	if len(data) >= 12 && string(data[:4]) == MagicHeader {
+		s.covered(0, 12)
+	}
+	pairs := make(map[string]string)
+	var errs []error
+	var split, embedded bool
+	var keyID, finalized string
+	var meta map[string]*entryMeta
+
+	for i := 0; i+4 <= len(data); {
+		name, next, ok := s.field(i)
+		if !ok {
+			i++
+			continue
+		}
+
+		if isReserved(string(name)) {
+			field, end, ok := s.field(next)
+			if !ok {
+				i++
+				continue
+			}
+			value := string(field)
+			switch string(name) {
+			case keyEntry:
+				if value != fmt.Sprintf("%x", key) {
+					errs = append(errs, fmt.Errorf("bytes %d to %d: embedded key does not match the recovery key", i, end))
+				}
+				embedded = true
+			case keyIDEntry:
+				keyID = value
+			case splitEntry:
+				split = value == splitHKDF
+			case finalEntry:
+				if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+					finalized = value
+				}
+			case metaEntry:
+				if m, ok := openMeta(field, plain, kek); ok {
+					meta = m
+				} else {
+					errs = append(errs, fmt.Errorf("bytes %d to %d: metadata could not be decrypted", i, end))
+				}
+			}
+			s.covered(i, end)
+			i = end
+			continue
+		}
+
+		raw, ok := decodeSealed(name)
+		if !ok {
+			i++
+			continue
+		}
+		values := plain
+		decKey, err := open(plain, nil, raw)
+		if err != nil {
+			if decKey, err = open(kek, nil, raw); err != nil {
+				i++
+				continue
+			}
+			values = vek
+			split = true
+		}
+
+		decValue, end, ok := s.sealed(next, values)
+		if !ok {
+			s.covered(i, next)
+			errs = append(errs, &RegionError{Offset: i, Length: next - i, Key: decKey})
+			i = next
+			continue
+		}
+
+		if _, dup := pairs[decKey]; dup {
+			errs = append(errs, fmt.Errorf("bytes %d to %d: %s appears more than once, kept the first", i, end, decKey))
+		} else {
+			pairs[decKey] = decValue
+		}
+		s.covered(i, end)
+		i = end
+	}
+	errs = append(errs, s.gaps()...)
+
+	c := newConfig("", nil)
+	if split {
+		c.DB[splitEntry] = splitHKDF
+	}
+	if keyID != "" {
+		c.DB[keyIDEntry] = keyID
+	}
+	if embedded {
+		c.DB[keyEntry] = fmt.Sprintf("%x", key)
+	} else if keyID == "" {
+		errs = append(errs, errors.New("neither an embedded key nor a key ID was recovered, so the key is not embedded"))
+	}
+	if err := c.initCipher(key); err != nil {
+		return nil, append(errs, err)
+	}
+
+	now := c.now()
+	for k, v := range pairs {
+		encKey, encValue, err := c.encryptPair(k, v)
+		if err != nil {
+			return nil, append(errs, err)
+		}
+		c.DB[encKey] = encValue
+		if m, ok := meta[k]; ok {
+			c.meta[k] = m
+		} else {
+			c.meta[k] = &entryMeta{Created: now, Updated: now}
+		}
+	}
+	if finalized != "" {
+		c.DB[finalEntry] = finalized
+		c.readOnly, c.sealed = true, true
+	}
+	c.loadBaseline()
+	return c, errs
+}
+
+// scan tracks which bytes of data RecoverFromBytes accounted for
+type scan struct {
+	data  []byte
+	spans [][2]int
+}
+
+// field locates a length-prefixed field at offset if one fits in data, and
+// returns it with the offset just past it
+func (s *scan) field(offset int) ([]byte, int, bool) {
+	if len(s.data)-offset < 4 {
+		return nil, 0, false
+	}
+	n := binary.BigEndian.Uint32(s.data[offset : offset+4])
+	start := offset + 4
+	if uint64(n) > uint64(len(s.data)-start) {
+		return nil, 0, false
+	}
+	end := start + int(n)
+	return s.data[start:end], end, true
+}
+
+// covered records data[start:end] as recovered
+func (s *scan) covered(start, end int) {
+	s.spans = append(s.spans, [2]int{start, end})
+}
+
+// gaps reports every range not covered by a recovered field
+func (s *scan) gaps() []error {
+	sort.Slice(s.spans, func(i, j int) bool {
+		return s.spans[i][0] < s.spans[j][0]
+	})
+	var errs []error
+	pos := 0
+	for _, span := range s.spans {
+		if span[0] > pos {
+			errs = append(errs, &RegionError{Offset: pos, Length: span[0] - pos})
+		}
+		if span[1] > pos {
+			pos = span[1]
+		}
+	}
+	if pos < len(s.data) {
+		errs = append(errs, &RegionError{Offset: pos, Length: len(s.data) - pos})
+	}
+	return errs
+}
+
+// sealed reads and decrypts a sealed field at offset
+func (s *scan) sealed(offset int, gcm cipher.AEAD) (string, int, bool) {
+	field, end, ok := s.field(offset)
+	if !ok {
+		return "", 0, false
+	}
+	raw, ok := decodeSealed(field)
+	if !ok {
+		return "", 0, false
+	}
+	value, err := open(gcm, nil, raw)
+	if err != nil {
+		return "", 0, false
+	}
+	return value, end, true
+}
+
+// openMeta decrypts a metadata field under either layout's name cipher
+func openMeta(value []byte, ciphers ...cipher.AEAD) (map[string]*entryMeta, bool) {
+	raw, ok := decodeSealed(value)
+	if !ok {
+		return nil, false
+	}
+	for _, gcm := range ciphers {
+		metaJSON, err := open(gcm, nil, raw)
+		if err != nil {
+			continue
+		}
+		var m map[string]*entryMeta
+		if err := json.Unmarshal([]byte(metaJSON), &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// decodeSealed decodes a base64 field that is long enough to be sealed
+func decodeSealed(field []byte) ([]byte, bool) {
+	if len(field) < minSealedField {
+		return nil, false
+	}
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(field)))
+	n, err := base64.StdEncoding.Decode(raw, field)
+	if err != nil {
+		return nil, false
+	}
+	return raw[:n], true
+}
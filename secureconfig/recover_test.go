@@ -0,0 +1,176 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ddelpero/secureconfig/format"
+)
+
+// entryRange returns where the entry for key lies in data, from its key
+// length through the end of its value
+func entryRange(t *testing.T, c *Config, data []byte, key string) (int, int) {
+	t.Helper()
+	encKey, ok := c.findEntry(key)
+	if !ok {
+		t.Fatalf("no entry for %s", key)
+	}
+	spans, _, err := format.Index(data, DefaultMaxEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := spans[encKey]
+	return value.Offset - 4 - len(encKey) - 4, value.Offset + value.Length
+}
+
+func TestRecoverFromBytes(t *testing.T) {
+	c, path := newTestConfig(t)
+	pairs := map[string]string{"a": "alpha", "b": "bravo", "c": "charlie", "d": "delta"}
+	mustStore(t, c, pairs)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite the whole of c's entry and the value of d
+	start, end := entryRange(t, c, data, "c")
+	dStart, dEnd := entryRange(t, c, data, "d")
+	copy(data[start:end], bytes.Repeat([]byte{0xff}, end-start))
+	copy(data[dEnd-8:dEnd], "XXXXXXXX")
+	if _, err := NewConfigWithFile(writeTemp(t, data)); err == nil {
+		t.Fatal("the damaged file still opens")
+	}
+
+	recovered, errs := RecoverFromBytes(data, c.Key)
+	if recovered == nil {
+		t.Fatalf("RecoverFromBytes returned no config: %v", errs)
+	}
+	for _, k := range []string{"a", "b"} {
+		if got, err := recovered.Retrieve(k); err != nil || got != pairs[k] {
+			t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, pairs[k])
+		}
+	}
+	for _, k := range []string{"c", "d"} {
+		if _, err := recovered.Retrieve(k); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Retrieve(%q) = %v, want ErrNotFound", k, err)
+		}
+	}
+
+	var wiped, lostValue bool
+	for _, err := range errs {
+		var region *RegionError
+		if !errors.As(err, &region) {
+			continue
+		}
+		if region.Offset <= start && region.Offset+region.Length >= end {
+			wiped = true
+		}
+		if region.Key == "d" && region.Offset == dStart {
+			lostValue = true
+		}
+	}
+	if !wiped {
+		t.Errorf("no region error covers the overwritten bytes %d to %d: %v", start, end, errs)
+	}
+	if !lostValue {
+		t.Errorf("no region error names d, whose value was damaged: %v", errs)
+	}
+
+	// The rescued config is written out clean
+	out := path + ".recovered"
+	if err := recovered.SaveAs(out); err != nil {
+		t.Fatalf("SaveAs: %v", err)
+	}
+	reopened, err := NewConfigWithFile(out)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if keys, _ := reopened.ListKeys(); len(keys) != 2 {
+		t.Errorf("ListKeys = %v, want a and b", keys)
+	}
+}
+
+func TestRecoverFromBytesWrongKey(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "alpha"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, errs := RecoverFromBytes(data, bytes.Repeat([]byte{0x01}, 32))
+	if recovered != nil {
+		if keys, _ := recovered.ListKeys(); len(keys) != 0 {
+			t.Errorf("recovered %v with the wrong key", keys)
+		}
+	}
+	if len(errs) == 0 {
+		t.Error("no errors reported for a wrong key")
+	}
+	if _, errs := RecoverFromBytes(data, []byte("short")); len(errs) == 0 {
+		t.Error("RecoverFromBytes accepted a short key")
+	}
+}
+
+// writeTemp writes data to a fresh file and returns its path
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "damaged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestRecoverFromBytesKeepsFinalize(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "alpha", "b": "bravo"})
+	if err := c.Finalize(); err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := entryRange(t, c, data, "b")
+	copy(data[start:end], bytes.Repeat([]byte{0xff}, end-start))
+
+	recovered, _ := RecoverFromBytes(data, c.Key)
+	if recovered == nil {
+		t.Fatal("RecoverFromBytes returned no config")
+	}
+	if !recovered.Finalized() {
+		t.Error("recovered config lost the finalize marker")
+	}
+	if err := recovered.Store("c", "charlie"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store on a recovered finalized config = %v, want ErrReadOnly", err)
+	}
+
+	// Saving the rescue keeps the seal on the new file
+	out := path + ".recovered"
+	if err := recovered.SaveAs(out); err != nil {
+		t.Fatalf("SaveAs: %v", err)
+	}
+	reopened, err := NewConfigWithFile(out)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if !reopened.Finalized() {
+		t.Error("reopened config lost the finalize marker")
+	}
+	if err := reopened.Store("c", "charlie"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store after reopening = %v, want ErrReadOnly", err)
+	}
+	if got, err := reopened.Retrieve("a"); err != nil || got != "alpha" {
+		t.Errorf("Retrieve(a) = %q, %v; want alpha", got, err)
+	}
+	if err := recovered.Store("c", "charlie"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store after SaveAs = %v, want ErrReadOnly", err)
+	}
+}
@@ -0,0 +1,247 @@
+package secureconfig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rekeyHook, when set, runs once Rekey has built the new state and before it
+// is swapped in, so tests can hold a rekey at that point
+var rekeyHook func()
+
+// Rekey re-encrypts every entry under newKey and writes the file once. A key
+// embedded in the file is replaced by newKey; with a KeyProvider the caller
+// must make newKey available to the provider under the file's key ID. On
+// error the configuration is left unchanged.
+//
+// The new state is built off to the side while reads continue against the
+// old one; only the final swap and file write block them. Other writes wait
+// until Rekey is done.
+//
+// On a handle from OpenWithKeys, Rekey is the only write allowed, and a
+// successful one ends the rollover and makes the handle writable.
+func (c *Config) Rekey(newKey []byte) error {
+	// Holding the writer lock keeps DB and the ciphers fixed, so they can be
+	// read without mu while building the new state
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.readOnly && !c.rollover {
+		return ErrReadOnly
+	}
+
+	if len(newKey) != 32 {
+		return fmt.Errorf("key must be 32 bytes, got %d", len(newKey))
+	}
+	names, values, err := newCiphers(newKey, c.splitKeys())
+	if err != nil {
+		return err
+	}
+	next := &Config{GCM: names}
+	if c.splitKeys() {
+		next.valueGCM = values
+	}
+
+	db := make(map[string]string, len(c.DB))
+	for k, v := range c.DB {
+		if isReserved(k) {
+			if k != metaEntry {
+				db[k] = v
+			}
+			continue
+		}
+		key, value, err := c.decryptPair(k, c.stored(k))
+		if err != nil {
+			return err
+		}
+		encKey, encValue, err := next.encryptPair(key, value)
+		if err != nil {
+			return err
+		}
+		db[encKey] = encValue
+	}
+	if _, ok := db[keyEntry]; ok {
+		db[keyEntry] = fmt.Sprintf("%x", newKey)
+	}
+	if rekeyHook != nil {
+		rekeyHook()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldDB, oldKey, oldGCM, oldValueGCM := c.DB, c.Key, c.GCM, c.valueGCM
+	oldFallback, oldValueFallback := c.fallback, c.valueFallback
+	c.DB, c.Key, c.GCM, c.valueGCM = db, newKey, next.GCM, next.valueGCM
+	c.fallback, c.valueFallback = nil, nil
+	c.readOnly = false
+	if err := c.writeSecretsFile(); err != nil {
+		c.DB, c.Key, c.GCM, c.valueGCM = oldDB, oldKey, oldGCM, oldValueGCM
+		c.fallback, c.valueFallback = oldFallback, oldValueFallback
+		c.readOnly = c.rollover
+		return err
+	}
+	c.rollover = false
+	return nil
+}
+
+// decryptPair decodes and decrypts one stored pair
+func (c *Config) decryptPair(encKey, encValue string) (string, string, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(encKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode key: %v", err)
+	}
+	key, err := c.Decrypt(keyBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decrypt key: %v", err)
+	}
+	valueBytes, err := base64.StdEncoding.DecodeString(encValue)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode value for %s: %v", key, err)
+	}
+	value, err := c.decryptValue(valueBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decrypt value for %s: %v", key, err)
+	}
+	return key, value, nil
+}
+
+// encryptPair encrypts and encodes one pair for the DB
+func (c *Config) encryptPair(key, value string) (string, string, error) {
+	encKeyBytes, err := c.Encrypt(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt key: %v", err)
+	}
+	encValueBytes, err := c.encryptValue(value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt value: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(encKeyBytes), base64.StdEncoding.EncodeToString(encValueBytes), nil
+}
+
+// FileError is a failure for one file in a bulk operation
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// RekeyDirectoryError lists the files RekeyDirectory could not rekey
+type RekeyDirectoryError struct {
+	Files []FileError
+}
+
+func (e *RekeyDirectoryError) Error() string {
+	msgs := make([]string, len(e.Files))
+	for i, f := range e.Files {
+		msgs[i] = fmt.Sprintf("%s: %v", f.Path, f.Err)
+	}
+	return fmt.Sprintf("failed to rekey %d files: %s", len(e.Files), strings.Join(msgs, "; "))
+}
+
+// RekeyDirectory rekeys every secureconfig file directly inside dir, asking
+// newKeys for each file's new key by its key ID. Files are opened with the
+// key currentKeys returns for their key ID, or with their embedded key when
+// currentKeys is nil, and each is rewritten atomically. A failing file does
+// not stop the run: the number of files rekeyed is returned together with a
+// *RekeyDirectoryError listing the failures. Files without the secureconfig
+// header are skipped, as are backups, temporary files and corrupted files
+// moved aside, which belong to the file they sit next to.
+//
+// currentKeys is needed besides newKeys because a file kept with a
+// KeyProvider holds no key of its own: without the provider that supplies
+// its current key it cannot be decrypted, and so cannot be rekeyed. Pass nil
+// when every file embeds its key.
+func RekeyDirectory(dir string, currentKeys, newKeys KeyProvider) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read directory: %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var failures []FileError
+	count := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || isAuxiliaryFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		ok, err := isConfigFile(path)
+		if err != nil {
+			failures = append(failures, FileError{Path: path, Err: err})
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := rekeyFile(path, currentKeys, newKeys); err != nil {
+			failures = append(failures, FileError{Path: path, Err: err})
+			continue
+		}
+		count++
+	}
+
+	if len(failures) > 0 {
+		return count, &RekeyDirectoryError{Files: failures}
+	}
+	return count, nil
+}
+
+// isConfigFile reports whether path starts with the secureconfig header
+func isConfigFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(MagicHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false, nil // too short to be a config file
+	}
+	return string(header) == MagicHeader, nil
+}
+
+// isAuxiliaryFile reports whether name is a rotated backup (<file>.bak.N), a
+// temporary file from an interrupted write (<file>.tmpN) or a corrupted file
+// moved aside by recovery (<file>.corrupt)
+func isAuxiliaryFile(name string) bool {
+	if base := strings.TrimSuffix(name, ".corrupt"); base != name && base != "" {
+		return true
+	}
+	for _, marker := range []string{".bak.", ".tmp"} {
+		i := strings.LastIndex(name, marker)
+		if i <= 0 {
+			continue
+		}
+		if _, err := strconv.ParseUint(name[i+len(marker):], 10, 64); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rekeyFile opens one file with its current key and rekeys it with the new
+// provider's key
+func rekeyFile(path string, currentKeys, newKeys KeyProvider) error {
+	var opts []Option
+	if currentKeys != nil {
+		opts = append(opts, WithKeyProvider(currentKeys))
+	}
+	c, err := NewConfigWithFile(path, opts...)
+	if err != nil {
+		return err
+	}
+	newKey, err := newKeys.Key(c.KeyID())
+	if err != nil {
+		return fmt.Errorf("failed to get key %q from provider: %v", c.KeyID(), err)
+	}
+	return c.Rekey(newKey)
+}
@@ -0,0 +1,163 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRekeyAllowsConcurrentReads(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"a": "alpha", "b": "bravo"})
+	old := &Config{GCM: c.GCM}
+	newKey := bytes.Repeat([]byte{0x5a}, 32)
+
+	// Hold the rekey once the new state is built, before it is swapped in
+	built, release := make(chan struct{}), make(chan struct{})
+	rekeyHook = func() {
+		close(built)
+		<-release
+	}
+	t.Cleanup(func() { rekeyHook = nil })
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Rekey(newKey)
+	}()
+	<-built
+
+	// Reads go on against the old state, still encrypted under the old key
+	if got, err := c.Retrieve("a"); err != nil || got != "alpha" {
+		t.Errorf("Retrieve during Rekey = %q, %v; want alpha", got, err)
+	}
+	if value, err := storedValue(c, old, "b"); err != nil || value != "bravo" {
+		t.Errorf("old cipher during Rekey = %q, %v; want bravo", value, err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if got, err := c.Retrieve("a"); err != nil || got != "alpha" {
+		t.Errorf("Retrieve after Rekey = %q, %v; want alpha", got, err)
+	}
+	if _, err := storedValue(c, old, "b"); err == nil {
+		t.Error("old cipher still decrypts the entries after Rekey")
+	}
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if !bytes.Equal(reopened.Key, newKey) {
+		t.Error("reopened file does not hold the new key")
+	}
+}
+
+// storedValue decrypts the DB entry c holds for key with the ciphers of using
+func storedValue(c, using *Config, key string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for k, v := range c.DB {
+		if isReserved(k) {
+			continue
+		}
+		if name, value, err := using.decryptPair(k, v); err == nil && name == key {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no entry for %s under these ciphers", ErrNotFound, key)
+}
+
+func TestRekeyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := bytes.Repeat([]byte{0x01}, 32)
+	current := &mapProvider{keys: map[string][]byte{"": oldKey}}
+	next := &mapProvider{keys: map[string][]byte{}}
+
+	ids := []string{"alpha", "beta", "gamma"}
+	for i, id := range ids {
+		current.keys[id] = oldKey
+		next.keys[id] = bytes.Repeat([]byte{byte(0x10 + i)}, 32)
+
+		c, err := NewConfigWithFile(filepath.Join(dir, id+".bin"), WithKeyProvider(current))
+		if err != nil {
+			t.Fatalf("NewConfigWithFile: %v", err)
+		}
+		if err := c.SetKeyID(id); err != nil {
+			t.Fatalf("SetKeyID: %v", err)
+		}
+		mustStore(t, c, map[string]string{"name": id})
+	}
+
+	// Backups and leftover temporary files carry the header but are not rekeyed
+	alpha, err := os.ReadFile(filepath.Join(dir, "alpha.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := []string{"alpha.bin.bak.1", "alpha.bin.tmp123456"}
+	for _, name := range skipped {
+		if err := os.WriteFile(filepath.Join(dir, name), alpha, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a config"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.bin")
+	if err := os.WriteFile(broken, []byte(MagicHeader+"\x00\x00\x00\x01garbage"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := RekeyDirectory(dir, current, next)
+	if n != len(ids) {
+		t.Errorf("rekeyed %d files, want %d", n, len(ids))
+	}
+	var dirErr *RekeyDirectoryError
+	if !errors.As(err, &dirErr) {
+		t.Fatalf("RekeyDirectory error = %v, want a *RekeyDirectoryError", err)
+	}
+	if len(dirErr.Files) != 1 || dirErr.Files[0].Path != broken {
+		t.Errorf("failures = %v, want only %s", dirErr.Files, broken)
+	}
+
+	for _, id := range ids {
+		provider := &mapProvider{keys: map[string][]byte{id: next.keys[id]}}
+		c, err := NewConfigWithFile(filepath.Join(dir, id+".bin"), WithKeyProvider(provider))
+		if err != nil {
+			t.Fatalf("%s does not open with its new key: %v", id, err)
+		}
+		if got, err := c.Retrieve("name"); err != nil || got != id {
+			t.Errorf("%s: Retrieve = %q, %v; want %q", id, got, err, id)
+		}
+	}
+	for _, name := range skipped {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, alpha) {
+			t.Errorf("%s was modified", name)
+		}
+	}
+}
+
+func TestIsAuxiliaryFile(t *testing.T) {
+	tests := map[string]bool{
+		"config.bin":           false,
+		"config.bin.bak.1":     true,
+		"config.bin.bak.12":    true,
+		"config.bin.tmp482910": true,
+		"config.bak.bin":       false,
+		"config.tmpl":          false,
+		"config.bin.corrupt":   true,
+		".corrupt":             false,
+		".bak.1":               false,
+	}
+	for name, want := range tests {
+		if got := isAuxiliaryFile(name); got != want {
+			t.Errorf("isAuxiliaryFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
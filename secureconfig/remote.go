@@ -0,0 +1,88 @@
+package secureconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxRemoteSize bounds how much NewConfigFromURL will download
+const maxRemoteSize = 64 << 20
+
+// WithHTTPClient sets the client NewConfigFromURL fetches with, for example
+// to trust a private CA. Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Config) {
+		c.httpClient = client
+	}
+}
+
+// NewConfigFromURL fetches an encrypted config file over HTTP(S) and opens it
+// read-only: every write returns ErrReadOnly. The key must come from a
+// KeyProvider set with WithKeyProvider, since a centrally hosted file should
+// not embed its key. The request honours ctx, and the download must match its
+// Content-Length and contain exactly one well-formed config.
+func NewConfigFromURL(ctx context.Context, url string, opts ...Option) (*Config, error) {
+	c := newConfig(url, opts)
+	if c.optErr != nil {
+		return nil, c.optErr
+	}
+	c.readOnly = true
+	if c.keyProvider == nil {
+		return nil, errors.New("a KeyProvider is required to open a config from a URL")
+	}
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config: %s", resp.Status)
+	}
+	if resp.ContentLength > maxRemoteSize {
+		return nil, fmt.Errorf("config is too large: %d bytes", resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	if len(data) > maxRemoteSize {
+		return nil, fmt.Errorf("config is larger than %d bytes", maxRemoteSize)
+	}
+	if resp.ContentLength >= 0 && int64(len(data)) != resp.ContentLength {
+		return nil, fmt.Errorf("config truncated: got %d of %d bytes", len(data), resp.ContentLength)
+	}
+
+	n, err := c.parseDB(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, fmt.Errorf("unexpected %d bytes after config data", len(data)-n)
+	}
+
+	key, err := c.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initCipher(key); err != nil {
+		return nil, err
+	}
+	if err := c.loadMeta(); err != nil {
+		return nil, err
+	}
+	c.loadBaseline()
+	return c, nil
+}
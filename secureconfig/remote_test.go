@@ -0,0 +1,134 @@
+package secureconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// servedConfig writes a config under a provider key and returns its bytes
+// with the provider that opens it
+func servedConfig(t *testing.T) ([]byte, KeyProvider) {
+	t.Helper()
+	p := &mapProvider{keys: map[string][]byte{"": bytes.Repeat([]byte{0x07}, 32)}}
+	c, path := newTestConfig(t, WithKeyProvider(p))
+	mustStore(t, c, map[string]string{"db.password": "hunter2", "api.token": "tok"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, p
+}
+
+func TestNewConfigFromURL(t *testing.T) {
+	data, p := servedConfig(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	c, err := NewConfigFromURL(context.Background(), srv.URL+"/config.bin", WithKeyProvider(p), WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("NewConfigFromURL: %v", err)
+	}
+	for k, want := range map[string]string{"db.password": "hunter2", "api.token": "tok"} {
+		if got, err := c.Retrieve(k); err != nil || got != want {
+			t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, want)
+		}
+	}
+	if err := c.Store("db.password", "changed"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store on a remote config = %v, want ErrReadOnly", err)
+	}
+
+	if _, err := NewConfigFromURL(context.Background(), srv.URL); err == nil {
+		t.Error("NewConfigFromURL opened a config without a KeyProvider")
+	}
+}
+
+func TestNewConfigFromURLRejectsBadDownloads(t *testing.T) {
+	data, p := servedConfig(t)
+	tests := map[string]http.HandlerFunc{
+		"short body": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)+16))
+			w.Write(data)
+		},
+		"trailing bytes": func(w http.ResponseWriter, r *http.Request) {
+			w.Write(append(append([]byte(nil), data...), "extra"...))
+		},
+		"not found": func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		"declared too large": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(maxRemoteSize+1))
+		},
+	}
+	for name, handler := range tests {
+		srv := httptest.NewServer(handler)
+		_, err := NewConfigFromURL(context.Background(), srv.URL, WithKeyProvider(p), WithHTTPClient(srv.Client()))
+		if err == nil {
+			t.Errorf("%s: NewConfigFromURL succeeded", name)
+		}
+		srv.Close()
+	}
+
+	// A transport that reports more bytes than the body holds, without the
+	// read error a real connection would give
+	short := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Status:        "200 OK",
+			ContentLength: int64(len(data) + 16),
+			Body:          io.NopCloser(bytes.NewReader(data)),
+			Request:       r,
+		}, nil
+	})}
+	_, err := NewConfigFromURL(context.Background(), "https://config.example/config.bin", WithKeyProvider(p), WithHTTPClient(short))
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("Content-Length mismatch: NewConfigFromURL error = %v, want truncated", err)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewConfigFromURLHonoursContext(t *testing.T) {
+	_, p := servedConfig(t)
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := NewConfigFromURL(ctx, srv.URL, WithKeyProvider(p), WithHTTPClient(srv.Client()))
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("NewConfigFromURL error = %v, want a cancellation", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("NewConfigFromURL took %v to notice the cancellation", elapsed)
+	}
+}
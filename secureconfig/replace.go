@@ -0,0 +1,139 @@
+package secureconfig
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplaceAll swaps the entire contents of the configuration for pairs in a
+// single write. Every pair is encrypted into a fresh DB that keeps the master
+// key settings before anything is written, so the file never shows a partial
+// or empty set. On error the configuration is left unchanged.
+func (c *Config) ReplaceAll(pairs map[string]string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+
+	now := c.now()
+	db := make(map[string]string, len(pairs)+2)
+	for k, v := range c.DB {
+		if isReserved(k) && k != metaEntry {
+			db[k] = v // master key and key ID carry over
+		}
+	}
+	meta := make(map[string]*entryMeta, len(pairs))
+	for key, value := range pairs {
+		if err := c.checkEntropy(key, value); err != nil {
+			return err
+		}
+		encKey, encValue, err := c.encryptPair(key, value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %v", key, err)
+		}
+		db[encKey] = encValue
+
+		created := now
+		var tags []string
+		if old, ok := c.meta[key]; ok && !old.Deleted {
+			// Replacing a soft deleted key starts a new entry
+			created, tags = old.Created, old.Tags
+		}
+		meta[key] = &entryMeta{Created: created, Updated: now, Tags: tags}
+	}
+
+	removed, err := c.listKeys()
+	if err != nil {
+		return err
+	}
+
+	oldDB, oldMeta := c.DB, c.meta
+	c.DB, c.meta = db, meta
+	if err := c.writeSecretsFile(); err != nil {
+		c.DB, c.meta = oldDB, oldMeta
+		return err
+	}
+	c.cache.clear()
+
+	ctx := context.Background()
+	for _, key := range removed {
+		if _, ok := pairs[key]; !ok {
+			c.recordChange(ctx, OpDelete, key)
+		}
+	}
+	for key := range pairs {
+		c.recordChange(ctx, OpStore, key)
+	}
+	return nil
+}
+
+// StoreAll stores every pair in a single write, replacing existing entries
+// for the same keys and keeping all others. Nothing is written unless every
+// pair encrypts, and on error the configuration is left unchanged.
+func (c *Config) StoreAll(pairs map[string]string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	now := c.now()
+	db := make(map[string]string, len(c.DB)+len(pairs))
+	for k, v := range c.DB {
+		db[k] = v
+	}
+	meta := make(map[string]*entryMeta, len(c.meta)+len(pairs))
+	for k, m := range c.meta {
+		meta[k] = m
+	}
+
+	// Drop the previous entries for the stored keys in one pass over the DB
+	c.eachKey(func(encKey, key string) bool {
+		if _, ok := pairs[key]; ok {
+			delete(db, encKey)
+		}
+		return true
+	})
+
+	for key, value := range pairs {
+		if err := c.checkEntropy(key, value); err != nil {
+			return err
+		}
+		encKey, encValue, err := c.encryptPair(key, value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %v", key, err)
+		}
+		db[encKey] = encValue
+
+		created := now
+		var tags []string
+		if old, ok := c.meta[key]; ok && !old.Deleted {
+			created, tags = old.Created, old.Tags
+		}
+		meta[key] = &entryMeta{Created: created, Updated: now, Tags: tags}
+	}
+
+	oldDB, oldMeta := c.DB, c.meta
+	c.DB, c.meta = db, meta
+	if err := c.writeSecretsFile(); err != nil {
+		c.DB, c.meta = oldDB, oldMeta
+		return err
+	}
+
+	ctx := context.Background()
+	for key := range pairs {
+		c.cache.remove(key)
+		c.recordChange(ctx, OpStore, key)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package secureconfig
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplaceAll(t *testing.T) {
+	// Every write rotates one more backup, so the backups count the writes
+	c, path := newTestConfig(t, WithBackupOnWrite(10))
+	mustStore(t, c, map[string]string{"old.a": "1", "old.b": "2", "kept": "3"})
+	before := countBackups(t, path)
+
+	if err := c.ReplaceAll(map[string]string{"kept": "new", "new.c": "4"}); err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	if writes := countBackups(t, path) - before; writes != 1 {
+		t.Errorf("ReplaceAll wrote the file %d times, want 1", writes)
+	}
+
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for _, c := range []*Config{c, reopened} {
+		for _, k := range []string{"old.a", "old.b"} {
+			if _, err := c.Retrieve(k); err == nil {
+				t.Errorf("%s still present after ReplaceAll", k)
+			}
+		}
+		for k, want := range map[string]string{"kept": "new", "new.c": "4"} {
+			if got, err := c.Retrieve(k); err != nil || got != want {
+				t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, want)
+			}
+		}
+		if keys, _ := c.ListKeys(); len(keys) != 2 {
+			t.Errorf("ListKeys = %v, want 2 keys", keys)
+		}
+	}
+}
+
+func TestReplaceAllOverSoftDeletedKey(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}
+	c, _ := newTestConfig(t, WithClock(clock.now))
+	mustStore(t, c, map[string]string{"k": "old"})
+	if err := c.SoftDelete("k"); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+
+	clock.t = clock.t.Add(time.Hour)
+	if err := c.ReplaceAll(map[string]string{"k": "new"}); err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	if got := c.meta["k"].Created; !got.Equal(clock.t) {
+		t.Errorf("Created = %v, want the replacement time %v", got, clock.t)
+	}
+	if got, err := c.Retrieve("k"); err != nil || got != "new" {
+		t.Errorf("Retrieve = %q, %v; want new", got, err)
+	}
+}
+
+func TestStoreAll(t *testing.T) {
+	c, path := newTestConfig(t, WithBackupOnWrite(10))
+	mustStore(t, c, map[string]string{"kept": "1", "changed": "2"})
+	before := countBackups(t, path)
+
+	if err := c.StoreAll(map[string]string{"changed": "new", "added": "3"}); err != nil {
+		t.Fatalf("StoreAll: %v", err)
+	}
+	if writes := countBackups(t, path) - before; writes != 1 {
+		t.Errorf("StoreAll wrote the file %d times, want 1", writes)
+	}
+
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for _, c := range []*Config{c, reopened} {
+		for k, want := range map[string]string{"kept": "1", "changed": "new", "added": "3"} {
+			if got, err := c.Retrieve(k); err != nil || got != want {
+				t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, want)
+			}
+		}
+		if keys, _ := c.ListKeys(); len(keys) != 3 {
+			t.Errorf("ListKeys = %v, want 3 keys", keys)
+		}
+	}
+}
+
+func TestStoreAllLeavesConfigUnchangedOnError(t *testing.T) {
+	c, path := newTestConfig(t, WithMinEntropyBits(40))
+	mustStore(t, c, map[string]string{"existing": "Zq8#vL2!pR6@xW4$"})
+
+	err := c.StoreAll(map[string]string{"strong": "Np3&kT9*mB5^yH7%", "weak": "aaaa"})
+	if err == nil {
+		t.Fatal("StoreAll accepted a weak value")
+	}
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for _, c := range []*Config{c, reopened} {
+		if _, err := c.Retrieve("strong"); err == nil {
+			t.Error("StoreAll stored part of a failed batch")
+		}
+		if keys, _ := c.ListKeys(); len(keys) != 1 {
+			t.Errorf("ListKeys = %v, want only the existing key", keys)
+		}
+	}
+}
+
+// countBackups returns how many rotated backups of path exist
+func countBackups(t *testing.T, path string) int {
+	t.Helper()
+	matches, err := filepath.Glob(path + ".bak.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(matches)
+}
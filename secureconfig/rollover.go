@@ -0,0 +1,61 @@
+package secureconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OpenWithKeys opens an existing file whose entries may be encrypted under
+// any of keys, as during a key rollover window. Each entry is decrypted with
+// whichever key authenticates it, and opening fails if any entry matches
+// none of them.
+//
+// The handle is read-only until Rekey ends the rollover: every other write
+// returns ErrReadOnly, since a file holding entries under several keys can
+// only be opened again with OpenWithKeys. Call Rekey with keys[0] to
+// re-encrypt every entry, replace a key embedded in the file and make the
+// handle writable.
+func OpenWithKeys(filename string, keys ...[]byte) (*Config, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %d must be 32 bytes, got %d", i, len(key))
+		}
+	}
+
+	c := newConfig(filename, nil)
+	if err := c.loadDB(); err != nil {
+		return nil, err
+	}
+	c.readOnly = true
+	c.rollover = !c.finalized()
+	if err := c.initCipher(keys[0]); err != nil {
+		return nil, err
+	}
+	for _, key := range keys[1:] {
+		names, values, err := newCiphers(key, c.splitKeys())
+		if err != nil {
+			return nil, err
+		}
+		c.fallback = append(c.fallback, names)
+		if c.splitKeys() {
+			c.valueFallback = append(c.valueFallback, values)
+		}
+	}
+
+	for k, v := range c.DB {
+		if isReserved(k) {
+			continue
+		}
+		if _, _, err := c.decryptPair(k, v); err != nil {
+			return nil, fmt.Errorf("entry cannot be decrypted with the supplied keys: %v", err)
+		}
+	}
+	if err := c.loadMeta(); err != nil {
+		return nil, err
+	}
+	c.loadBaseline()
+	return c, nil
+}
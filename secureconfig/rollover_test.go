@@ -0,0 +1,106 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ddelpero/secureconfig/format"
+)
+
+// splitFile writes a file holding a and b under one key and c under another,
+// as left midway through a rollover, and returns the old and new keys
+func splitFile(t *testing.T, path string) (oldKey, newKey []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	old := openTestConfig(t, filepath.Join(dir, "old.bin"))
+	mustStore(t, old, map[string]string{"a": "1", "b": "2"})
+	next := openTestConfig(t, filepath.Join(dir, "new.bin"))
+	mustStore(t, next, map[string]string{"c": "3"})
+
+	db := make(map[string]string)
+	for k, v := range old.DB {
+		db[k] = v
+	}
+	for k, v := range next.DB {
+		if !isReserved(k) {
+			db[k] = v
+		}
+	}
+	data, err := format.Encode(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return old.Key, next.Key
+}
+
+// openTestConfig opens a config at path or fails the test
+func openTestConfig(t *testing.T, path string) *Config {
+	t.Helper()
+	c, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	return c
+}
+
+func TestOpenWithKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.bin")
+	oldKey, newKey := splitFile(t, path)
+	want := map[string]string{"a": "1", "b": "2", "c": "3"}
+
+	for _, key := range [][]byte{oldKey, newKey} {
+		if _, err := OpenWithKeys(path, key); err == nil {
+			t.Error("OpenWithKeys opened the split file with only one key")
+		}
+	}
+
+	c, err := OpenWithKeys(path, newKey, oldKey)
+	if err != nil {
+		t.Fatalf("OpenWithKeys: %v", err)
+	}
+	for k, v := range want {
+		if got, err := c.Retrieve(k); err != nil || got != v {
+			t.Errorf("Retrieve(%q) = %q, %v; want %q", k, got, err, v)
+		}
+	}
+
+	// Writes other than Rekey would leave the file half under each key
+	before, _ := os.ReadFile(path)
+	if err := c.Store("d", "4"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store during rollover = %v, want ErrReadOnly", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("file changed during rollover")
+	}
+
+	if err := c.Rekey(newKey); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if err := c.Store("d", "4"); err != nil {
+		t.Errorf("Store after Rekey: %v", err)
+	}
+	want["d"] = "4"
+
+	// The embedded key is now the new one and opens every entry
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if !bytes.Equal(reopened.Key, newKey) {
+		t.Error("embedded key is not the new key after Rekey")
+	}
+	for k, v := range want {
+		if got, err := reopened.Retrieve(k); err != nil || got != v {
+			t.Errorf("after Rekey: Retrieve(%q) = %q, %v; want %q", k, got, err, v)
+		}
+	}
+	if _, err := OpenWithKeys(path, oldKey); err == nil {
+		t.Error("the old key still opens the file after Rekey")
+	}
+}
@@ -0,0 +1,372 @@
+package secureconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError lists every way the assembled config failed its schema
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("config does not match schema: %s", strings.Join(e.Errors, "; "))
+}
+
+// BindWithSchema assembles every decrypted value into a JSON document, where
+// dotted keys become nested objects (db.port is {"db": {"port": ...}}),
+// validates it against a JSON schema and then unmarshals it into v.
+//
+// Values are stored as strings, so a value is converted to a number, boolean,
+// array or object where the schema asks for one. Numbers must be finite and
+// in JSON syntax, so a value such as NaN or 0x1p3 stays a string and fails
+// validation. Validation problems are returned together as a
+// *ValidationError.
+//
+// The supported schema keywords are type, properties, required,
+// additionalProperties, items, enum, const, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, minLength, maxLength and pattern.
+// Other keywords are ignored.
+func (c *Config) BindWithSchema(v any, schemaJSON []byte) error {
+	var schema map[string]any
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		return fmt.Errorf("failed to parse schema: %v", err)
+	}
+
+	c.mu.RLock()
+	doc, err := c.document()
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	coerced := coerce(doc, schema)
+
+	var errs []string
+	validate(coerced, schema, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	data, err := json.Marshal(coerced)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to bind config: %v", err)
+	}
+	return nil
+}
+
+// document nests the decrypted values by their dotted key segments
+func (c *Config) document() (map[string]any, error) {
+	keys, err := c.listKeys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	doc := make(map[string]any)
+	for _, key := range keys {
+		value, err := c.retrieve(key)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(key, ".")
+		node := doc
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part]
+			if !ok {
+				child = make(map[string]any)
+				node[part] = child
+			}
+			obj, ok := child.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %s conflicts with a value stored at %s", key, part)
+			}
+			node = obj
+		}
+		leaf := parts[len(parts)-1]
+		if _, ok := node[leaf]; ok {
+			return nil, fmt.Errorf("key %s conflicts with keys nested below it", key)
+		}
+		node[leaf] = value
+	}
+	return doc, nil
+}
+
+// coerce converts string values to the type the schema expects where possible
+func coerce(value any, schema map[string]any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for name, child := range v {
+			if sub, ok := props[name].(map[string]any); ok {
+				v[name] = coerce(child, sub)
+			} else if extra != nil {
+				v[name] = coerce(child, extra)
+			}
+		}
+		return v
+	case string:
+		for _, t := range schemaTypes(schema) {
+			switch t {
+			case "integer":
+				if _, err := strconv.ParseInt(v, 10, 64); err == nil && jsonNumber(v) {
+					return json.Number(v)
+				}
+			case "number":
+				if jsonNumber(v) {
+					return json.Number(v)
+				}
+			case "boolean":
+				if b, err := strconv.ParseBool(v); err == nil {
+					return b
+				}
+			case "array", "object":
+				var decoded any
+				if err := json.Unmarshal([]byte(v), &decoded); err == nil {
+					return coerce(decoded, schema)
+				}
+			case "string":
+				return v
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i := range v {
+				v[i] = coerce(v[i], items)
+			}
+		}
+	}
+	return value
+}
+
+// jsonNumber reports whether s is a finite number in JSON syntax. strconv
+// also accepts NaN, Inf, hex floats and a leading + or zeros, which JSON
+// cannot encode, so those stay strings and fail validation as such.
+func jsonNumber(s string) bool {
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) && json.Valid([]byte(s))
+}
+
+// validate appends a message to errs for every schema violation under path
+func validate(value any, schema map[string]any, path string, errs *[]string) {
+	at := path
+	if at == "" {
+		at = "/"
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(types, " or "), typeName(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if equalJSON(value, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("%s is not one of the allowed values", describe(value))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !equalJSON(value, constant) {
+		fail("%s does not equal the required value", describe(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := v[name]; !present {
+						fail("missing required property %q", name)
+					}
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			childPath := path + "/" + name
+			if sub, ok := props[name].(map[string]any); ok {
+				validate(v[name], sub, childPath, errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", name)
+				}
+			case map[string]any:
+				validate(v[name], extra, childPath, errs)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validate(item, items, fmt.Sprintf("%s/%d", path, i), errs)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := schema["minLength"].(float64); ok && length < min {
+			fail("length %v is shorter than minLength %v", length, min)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && length > max {
+			fail("length %v is longer than maxLength %v", length, max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				fail("invalid pattern %q in schema: %v", pattern, err)
+			} else if !re.MatchString(v) {
+				fail("value does not match pattern %q", pattern)
+			}
+		}
+	default:
+		n, ok := toFloat(value)
+		if !ok {
+			break
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			fail("%v is less than minimum %v", n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			fail("%v is greater than maximum %v", n, max)
+		}
+		if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+			fail("%v is not greater than exclusiveMinimum %v", n, min)
+		}
+		if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+			fail("%v is not less than exclusiveMaximum %v", n, max)
+		}
+	}
+}
+
+// schemaTypes returns the type keyword as a list
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// hasType reports whether value is an instance of the JSON schema type t
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+// typeName names the JSON type of value for error messages
+func typeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// describe renders a value for error messages, strings are not echoed since
+// they may be secrets
+func describe(value any) string {
+	if n, ok := toFloat(value); ok {
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	}
+	if b, ok := value.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+	return "value of type " + typeName(value)
+}
+
+// toFloat converts the numeric representations that appear in a document
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equalJSON compares two JSON values, treating numbers by value
+func equalJSON(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
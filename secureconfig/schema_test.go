@@ -0,0 +1,87 @@
+package secureconfig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["db"],
+	"properties": {
+		"db": {
+			"type": "object",
+			"required": ["host", "port"],
+			"properties": {
+				"host": {"type": "string", "minLength": 1},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"tls": {"type": "boolean"}
+			}
+		}
+	}
+}`
+
+type testDBConfig struct {
+	DB struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+		TLS  bool   `json:"tls"`
+	} `json:"db"`
+}
+
+func TestBindWithSchema(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"db.host": "db.internal", "db.port": "5432", "db.tls": "true"})
+
+	var cfg testDBConfig
+	if err := c.BindWithSchema(&cfg, []byte(testSchema)); err != nil {
+		t.Fatalf("BindWithSchema: %v", err)
+	}
+	if cfg.DB.Host != "db.internal" || cfg.DB.Port != 5432 || !cfg.DB.TLS {
+		t.Errorf("bound %+v, want host db.internal, port 5432, tls true", cfg.DB)
+	}
+}
+
+func TestBindWithSchemaRejectsOutOfRangePort(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"db.host": "db.internal", "db.port": "70000"})
+
+	var cfg testDBConfig
+	err := c.BindWithSchema(&cfg, []byte(testSchema))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("BindWithSchema error = %v, want a *ValidationError", err)
+	}
+	if len(verr.Errors) != 1 || !strings.HasPrefix(verr.Errors[0], "/db/port:") {
+		t.Errorf("validation errors = %q, want one about /db/port", verr.Errors)
+	}
+	if cfg.DB.Port != 0 {
+		t.Errorf("BindWithSchema bound port %d despite failing validation", cfg.DB.Port)
+	}
+}
+
+func TestBindWithSchemaRejectsNumbersJSONCannotEncode(t *testing.T) {
+	schema := `{"type": "object", "properties": {
+		"ratio": {"type": "number"},
+		"count": {"type": "integer"}
+	}}`
+	for _, value := range []string{"NaN", "Inf", "-Infinity", "1e400", "0x1p3", "+5", "007"} {
+		c, _ := newTestConfig(t)
+		mustStore(t, c, map[string]string{"ratio": value, "count": value})
+
+		var cfg struct {
+			Ratio float64 `json:"ratio"`
+			Count int     `json:"count"`
+		}
+		err := c.BindWithSchema(&cfg, []byte(schema))
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: BindWithSchema error = %v, want a *ValidationError", value, err)
+			continue
+		}
+		if len(verr.Errors) != 2 {
+			t.Errorf("%s: validation errors = %q, want one each for /count and /ratio", value, verr.Errors)
+		}
+	}
+}
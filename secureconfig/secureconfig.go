@@ -1,24 +1,63 @@
 package secureconfig
 
 import (
-	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ddelpero/secureconfig/format"
 )
 
 // ConfigFile is the default configuration file name
 const ConfigFile = "config"
 
-// Magic header to identify secureconfig files
-const MagicHeader = "SCFG"
-const Version = 1
+// Magic header to identify secureconfig files, the layout is specified by
+// the format package
+const MagicHeader = format.Magic
+const Version = format.Version
+
+// DefaultMaxEntries is the most entries a file may declare unless changed
+// with WithMaxEntries
+const DefaultMaxEntries = 1 << 20
+
+// Reserved DB entries that are not encrypted key/value pairs
+const (
+	keyEntry   = "k"   // hex encoded master key
+	keyIDEntry = "kid" // identifier of an externally managed key
+	metaEntry  = "m"   // encrypted entry metadata
+	splitEntry = "s"   // key derivation when names and values use separate keys
+	finalEntry = "f"   // time the config was sealed with Finalize
+)
+
+// Errors returned by Config methods, match them with errors.Is
+var (
+	// ErrNotFound is returned when a key is not stored
+	ErrNotFound = errors.New("key not found")
+	// ErrReadOnly is returned by writes to a read-only config
+	ErrReadOnly = errors.New("config is read-only")
+	// ErrCorrupted is returned when a config file cannot be parsed
+	ErrCorrupted = format.ErrCorrupted
+	// ErrKeyOnly is returned when a key-only handle is asked for a value
+	ErrKeyOnly = errors.New("config handle holds only the key-encryption key")
+	// ErrWeakSecret is returned when a value fails WithMinEntropyBits
+	ErrWeakSecret = errors.New("secret is too weak")
+	// ErrPlaintextExport is returned by exports of plaintext values on a
+	// config opened without WithPlaintextExport
+	ErrPlaintextExport = errors.New("plaintext export is not enabled")
+)
 
 // Config holds the encryption configuration and data
 type Config struct {
@@ -26,44 +65,158 @@ type Config struct {
 	Key        []byte
 	GCM        cipher.AEAD
 	DB         map[string]string
+
+	fallback []cipher.AEAD // older keys accepted for decryption only
+
+	// File contents and value locations kept by WithLazyLoad
+	raw   []byte
+	spans map[string]format.Span
+
+	// Values use their own cipher when keys are split, see WithSplitKeys
+	valueGCM      cipher.AEAD
+	valueFallback []cipher.AEAD
+
+	writeMu sync.Mutex // serializes writers, always taken before mu
+
+	mu     sync.RWMutex // guards DB, meta and Key/GCM
+	meta   map[string]*entryMeta
+	logger *log.Logger
+	clock  func() time.Time
+	audit  io.Writer
+	subs   subscribers
+	cache  *valueCache
+
+	keyProvider KeyProvider
+	strictPerms bool
+	readOnly    bool
+	rollover    bool // opened with OpenWithKeys, only Rekey may write
+	sealed      bool // read-only only because of Finalize, SaveAs may copy
+	keyOnly     bool
+	newSplit    bool
+	backups     int
+	maxEntries  int
+	minEntropy  float64
+	lazy        bool
+	skewCheck   bool
+	plainExport bool
+	optErr      error // first invalid option, returned by the constructor
+	httpClient  *http.Client
+	rotateAfter time.Duration // age at which the manifest reports rotation due
+
+	clockMu  sync.Mutex
+	baseline time.Time // newest timestamp seen, guards against clock skew
+	skewed   bool
+}
+
+// entryMeta holds non-secret bookkeeping about a stored entry
+type entryMeta struct {
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Deleted bool      `json:"deleted,omitempty"` // soft deleted, see SoftDelete
+	Tags    []string  `json:"tags,omitempty"`    // inventory labels, see SetTags
+}
+
+// isReserved reports whether a DB entry is internal rather than a stored pair
+func isReserved(k string) bool {
+	return k == keyEntry || k == keyIDEntry || k == metaEntry || k == splitEntry || k == finalEntry
 }
 
 // NewConfig creates a new secure configuration instance
-func NewConfig() (*Config, error) {
-	return NewConfigWithFile(ConfigFile)
+func NewConfig(opts ...Option) (*Config, error) {
+	return NewConfigWithFile(ConfigFile, opts...)
 }
 
 // NewConfigWithFile creates a new secure configuration instance with custom file
-func NewConfigWithFile(filename string) (*Config, error) {
-	c := &Config{
-		ConfigFile: filename,
-		DB:         make(map[string]string),
+func NewConfigWithFile(filename string, opts ...Option) (*Config, error) {
+	c := newConfig(filename, opts)
+	if c.optErr != nil {
+		return nil, c.optErr
 	}
 
 	configPath := findDataFile(c.ConfigFile)
 	fileExists := true
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		fileExists = false
-		// Generate new key if config doesn't exist
-		key := make([]byte, 32) // 256-bit key for AES-256
-		if _, err := io.ReadFull(rand.Reader, key); err != nil {
-			return nil, fmt.Errorf("failed to generate key: %v", err)
+		// Generate new key if config doesn't exist and none is provided
+		if c.keyProvider == nil {
+			key := make([]byte, 32) // 256-bit key for AES-256
+			if _, err := io.ReadFull(rand.Reader, key); err != nil {
+				return nil, fmt.Errorf("failed to generate key: %v", err)
+			}
+			// Store key as hex string for binary format
+			c.DB[keyEntry] = fmt.Sprintf("%x", key)
 		}
-		// Store key as hex string for binary format
-		c.DB["k"] = fmt.Sprintf("%x", key)
-		if err := c.writeSecretsFile(); err != nil {
-			return nil, err
+		if c.newSplit {
+			c.DB[splitEntry] = splitHKDF
 		}
 	}
 
 	if fileExists {
 		if err := c.loadDB(); err != nil {
+			if c.backups == 0 || !errors.Is(err, ErrCorrupted) {
+				return nil, err
+			}
+			if err := c.recoverFromBackup(configPath, err); err != nil {
+				return nil, err
+			}
+		}
+		if c.finalized() {
+			c.readOnly, c.sealed = true, true
+		}
+	}
+
+	key, err := c.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initCipher(key); err != nil {
+		return nil, err
+	}
+
+	if fileExists {
+		if err := c.loadMeta(); err != nil {
 			return nil, err
 		}
+		c.loadBaseline()
+		c.now() // warn early if the clock is behind the stored data
+	} else if err := c.writeSecretsFile(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newConfig returns an empty Config with opts applied
+func newConfig(filename string, opts []Option) *Config {
+	c := &Config{
+		ConfigFile: filename,
+		DB:         make(map[string]string),
+		meta:       make(map[string]*entryMeta),
+		logger:     defaultLogger(),
+		clock:      time.Now,
+		maxEntries: DefaultMaxEntries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// masterKey returns the key from the provider, or the one embedded in the file
+func (c *Config) masterKey() ([]byte, error) {
+	if c.keyProvider != nil {
+		key, err := c.keyProvider.Key(c.keyID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key %q from provider: %v", c.keyID(), err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("provider key must be 32 bytes, got %d", len(key))
+		}
+		return key, nil
 	}
 
 	// Decode the key from hex
-	keyStr, ok := c.DB["k"]
+	keyStr, ok := c.DB[keyEntry]
 	if !ok {
 		return nil, fmt.Errorf("key not found in database")
 	}
@@ -73,10 +226,27 @@ func NewConfigWithFile(filename string) (*Config, error) {
 	if _, err := fmt.Sscanf(keyStr, "%x", &key); err != nil {
 		return nil, fmt.Errorf("failed to parse key: %v", err)
 	}
+	return key, nil
+}
+
+// initCipher sets the master key and initializes the AES-GCM ciphers
+func (c *Config) initCipher(key []byte) error {
+	names, values, err := newCiphers(key, c.splitKeys())
+	if err != nil {
+		return err
+	}
 	c.Key = key
+	c.GCM = names
+	c.valueGCM = nil
+	if c.splitKeys() {
+		c.valueGCM = values
+	}
+	return nil
+}
 
-	// Initialize AES-GCM cipher
-	block, err := aes.NewCipher(c.Key)
+// newGCM creates an AES-GCM AEAD for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %v", err)
 	}
@@ -85,75 +255,202 @@ func NewConfigWithFile(filename string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCM: %v", err)
 	}
-	c.GCM = gcm
-
-	return c, nil
+	return gcm, nil
 }
 
 // Store encrypts and stores a key-value pair
 func (c *Config) Store(key, value string) error {
-	encKeyBytes, err := c.Encrypt(key)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt key: %v", err)
+	return c.StoreWithContext(context.Background(), key, value)
+}
+
+// StoreWithContext encrypts and stores a key-value pair, recording the actor
+// and reason from ctx in the audit log
+func (c *Config) StoreWithContext(ctx context.Context, key, value string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
 	}
-	encKey := base64.StdEncoding.EncodeToString(encKeyBytes)
+	if err := c.checkEntropy(key, value); err != nil {
+		return err
+	}
+
+	now := c.now()
+	meta, ok := c.meta[key]
+	if !ok || meta.Deleted {
+		// Storing over a soft deleted key starts a new entry
+		meta = &entryMeta{Created: now}
+	}
+	meta.Updated = now
 
-	encValueBytes, err := c.Encrypt(value)
+	encKey, encValue, err := c.encryptPair(key, value)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt value: %v", err)
+		return err
 	}
-	encValue := base64.StdEncoding.EncodeToString(encValueBytes)
 
+	// Replace any previous entry for this key, its ciphertext differs per nonce
+	if old, ok := c.findEntry(key); ok {
+		delete(c.DB, old)
+	}
 	c.DB[encKey] = encValue
-	return c.writeSecretsFile()
+	c.meta[key] = meta
+	c.cache.remove(key)
+	if err := c.writeSecretsFile(); err != nil {
+		return err
+	}
+	c.recordChange(ctx, OpStore, key)
+	return nil
 }
 
 // Retrieve decrypts and returns a value by key
 func (c *Config) Retrieve(key string) (string, error) {
-	for k, v := range c.DB {
-		if k != "k" {
-			// Decode base64 key
-			keyBytes, err := base64.StdEncoding.DecodeString(k)
-			if err != nil {
-				continue // Skip invalid entries
-			}
-			decKey, err := c.Decrypt(keyBytes)
-			if err != nil {
-				continue // Skip invalid entries
-			}
-			if decKey == key {
-				// Decode base64 value
-				valueBytes, err := base64.StdEncoding.DecodeString(v)
-				if err != nil {
-					continue // Skip invalid entries
-				}
-				return c.Decrypt(valueBytes)
-			}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.retrieve(key)
+}
+
+// retrieve is Retrieve for callers already holding the lock
+func (c *Config) retrieve(key string) (string, error) {
+	if value, ok := c.cache.get(key); ok {
+		return value, nil
+	}
+	k, ok := c.findEntry(key)
+	if !ok || c.softDeleted(key) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	// Decode base64 value
+	valueBytes, err := base64.StdEncoding.DecodeString(c.stored(k))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode value: %v", err)
+	}
+	value, err := c.decryptValue(valueBytes)
+	if err != nil {
+		return "", err
+	}
+	c.cache.put(key, value)
+	return value, nil
+}
+
+// RetrieveLimited decrypts a value by key and caps it at max bytes, reporting
+// whether it was truncated. Truncation never splits a UTF-8 sequence. If max
+// is zero or negative the value is returned in full.
+func (c *Config) RetrieveLimited(key string, max int) (string, bool, error) {
+	value, err := c.Retrieve(key)
+	if err != nil {
+		return "", false, err
+	}
+	if max <= 0 || len(value) <= max {
+		return value, false, nil
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut], true, nil
+}
+
+// findEntry returns the encrypted DB key holding the given plaintext key
+func (c *Config) findEntry(key string) (string, bool) {
+	var found string
+	c.eachKey(func(encKey, decKey string) bool {
+		if decKey == key {
+			found = encKey
+			return false
+		}
+		return true
+	})
+	return found, found != ""
+}
+
+// eachKey calls fn with the DB name and decrypted key name of every stored
+// entry until fn returns false. Internal entries are skipped, and so are
+// entries whose name does not decode or decrypt; eachKey returns how many
+// of those it skipped.
+func (c *Config) eachKey(fn func(encKey, key string) bool) int {
+	skipped := 0
+	for k := range c.DB {
+		if isReserved(k) {
+			continue
+		}
+		// Decode base64 key
+		keyBytes, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			skipped++ // Skip invalid entries
+			continue
+		}
+		decKey, err := c.Decrypt(keyBytes)
+		if err != nil {
+			skipped++ // Skip invalid entries
+			continue
+		}
+		if !fn(k, decKey) {
+			break
 		}
 	}
-	return "", fmt.Errorf("key not found: %s", key)
+	return skipped
 }
 
-// Encrypt encrypts a string using AES-GCM and returns raw bytes
+// Encrypt encrypts a string using AES-GCM and returns raw bytes. With split
+// keys this is the key-encryption key, which covers key names and metadata.
 func (c *Config) Encrypt(value string) ([]byte, error) {
-	nonce := make([]byte, c.GCM.NonceSize())
+	return seal(c.GCM, value)
+}
+
+// Decrypt decrypts raw bytes using AES-GCM
+func (c *Config) Decrypt(data []byte) (string, error) {
+	return open(c.GCM, c.fallback, data)
+}
+
+// encryptValue encrypts a stored value, under the value-encryption key when
+// keys are split
+func (c *Config) encryptValue(value string) ([]byte, error) {
+	if c.keyOnly {
+		return nil, ErrKeyOnly
+	}
+	if c.valueGCM == nil {
+		return c.Encrypt(value)
+	}
+	return seal(c.valueGCM, value)
+}
+
+// decryptValue decrypts a stored value, see encryptValue
+func (c *Config) decryptValue(data []byte) (string, error) {
+	if c.keyOnly {
+		return "", ErrKeyOnly
+	}
+	if c.valueGCM == nil {
+		return c.Decrypt(data)
+	}
+	return open(c.valueGCM, c.valueFallback, data)
+}
+
+// seal encrypts value under gcm with a random nonce prepended
+func seal(gcm cipher.AEAD, value string) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %v", err)
 	}
 
-	ciphertext := c.GCM.Seal(nonce, nonce, []byte(value), nil)
+	ciphertext := gcm.Seal(nonce, nonce, []byte(value), nil)
 	return ciphertext, nil
 }
 
-// Decrypt decrypts raw bytes using AES-GCM
-func (c *Config) Decrypt(data []byte) (string, error) {
-	nonceSize := c.GCM.NonceSize()
+// open decrypts data sealed under gcm or any of the fallback ciphers
+func open(gcm cipher.AEAD, fallback []cipher.AEAD, data []byte) (string, error) {
+	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
 		return "", fmt.Errorf("ciphertext too short")
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := c.GCM.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	// Entries written before a key rollover open with an older key
+	for i := 0; err != nil && i < len(fallback); i++ {
+		plaintext, err = fallback[i].Open(nil, nonce, ciphertext, nil)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt: %v", err)
 	}
@@ -161,46 +458,103 @@ func (c *Config) Decrypt(data []byte) (string, error) {
 	return string(plaintext), nil
 }
 
-// ListKeys returns all available keys (decrypted)
+// ListKeys returns all available keys (decrypted), as a snapshot that
+// concurrent writes do not affect
 func (c *Config) ListKeys() ([]string, error) {
-	var keys []string
-	for k := range c.DB {
-		if k != "k" {
-			// Decode base64 key
-			keyBytes, err := base64.StdEncoding.DecodeString(k)
-			if err != nil {
-				continue // Skip invalid entries
-			}
-			decKey, err := c.Decrypt(keyBytes)
-			if err != nil {
-				continue // Skip invalid entries
-			}
-			keys = append(keys, decKey)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.listKeys()
+}
+
+// ForEachKey calls fn for every key, stopping at the first error. It iterates
+// a snapshot taken under the read lock, so fn runs without holding the lock
+// and may itself read or write the config.
+func (c *Config) ForEachKey(fn func(key string) error) error {
+	keys, err := c.ListKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// listKeys is ListKeys for callers already holding the lock
+func (c *Config) listKeys() ([]string, error) {
+	var keys []string
+	c.eachKey(func(_, key string) bool {
+		if !c.softDeleted(key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
 	return keys, nil
 }
 
 // Delete removes a key-value pair
 func (c *Config) Delete(key string) error {
-	for k := range c.DB {
-		if k != "k" {
-			// Decode base64 key
-			keyBytes, err := base64.StdEncoding.DecodeString(k)
-			if err != nil {
-				continue
-			}
-			decKey, err := c.Decrypt(keyBytes)
-			if err != nil {
-				continue
-			}
-			if decKey == key {
-				delete(c.DB, k)
-				return c.writeSecretsFile()
-			}
-		}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+
+	k, ok := c.findEntry(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	delete(c.DB, k)
+	delete(c.meta, key)
+	c.cache.remove(key)
+	if err := c.writeSecretsFile(); err != nil {
+		return err
+	}
+	c.recordChange(context.Background(), OpDelete, key)
+	return nil
+}
+
+// loadMeta decrypts the entry metadata, files written before it existed have none
+func (c *Config) loadMeta() error {
+	encMeta, ok := c.DB[metaEntry]
+	if !ok {
+		return nil
+	}
+	metaBytes, err := base64.StdEncoding.DecodeString(encMeta)
+	if err != nil {
+		return fmt.Errorf("failed to decode metadata: %v", err)
+	}
+	metaJSON, err := c.Decrypt(metaBytes)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt metadata: %v", err)
+	}
+	if err := json.Unmarshal([]byte(metaJSON), &c.meta); err != nil {
+		return fmt.Errorf("failed to parse metadata: %v", err)
+	}
+	return nil
+}
+
+// storeMeta encrypts the entry metadata into its reserved DB entry
+func (c *Config) storeMeta() error {
+	if len(c.meta) == 0 {
+		delete(c.DB, metaEntry)
+		return nil
+	}
+	metaJSON, err := json.Marshal(c.meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %v", err)
+	}
+	encMeta, err := c.Encrypt(string(metaJSON))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt metadata: %v", err)
 	}
-	return fmt.Errorf("key not found: %s", key)
+	c.DB[metaEntry] = base64.StdEncoding.EncodeToString(encMeta)
+	return nil
 }
 
 func (c *Config) loadDB() error {
@@ -209,115 +563,128 @@ func (c *Config) loadDB() error {
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %v", err)
 	}
+	_, err = c.parseDB(data)
+	return err
+}
 
-	// Check magic header
-	if len(data) < 8 {
-		return fmt.Errorf("file too short")
+// parseDB decodes the binary format into c.DB and returns the number of bytes
+// it consumed
+func (c *Config) parseDB(data []byte) (int, error) {
+	if c.lazy {
+		return c.parseLazy(data)
 	}
-	if string(data[:4]) != MagicHeader {
-		return fmt.Errorf("invalid file format")
+	db, n, err := format.Decode(data, c.maxEntries)
+	if err != nil {
+		return 0, err
 	}
+	c.DB = db
+	return n, nil
+}
 
-	// Check version
-	version := binary.BigEndian.Uint32(data[4:8])
-	if version != Version {
-		return fmt.Errorf("unsupported version: %d", version)
+// SaveAs writes the config to filename, which becomes its file for later
+// writes. On error the config keeps its previous file. A config sealed with
+// Finalize can be saved too, and the copy stays sealed.
+func (c *Config) SaveAs(filename string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The seal is written with the copy, so saving does not break it. A handle
+	// that is read-only for another reason stays that way.
+	if c.sealed {
+		c.readOnly = false
+		defer func() { c.readOnly = true }()
 	}
 
-	// Read number of entries
-	offset := 8
-	if len(data) < offset+4 {
-		return fmt.Errorf("file too short for entry count")
+	old := c.ConfigFile
+	c.ConfigFile = filename
+	if err := c.writeSecretsFile(); err != nil {
+		c.ConfigFile = old
+		return err
 	}
-	numEntries := binary.BigEndian.Uint32(data[offset : offset+4])
-	offset += 4
-
-	// Read entries
-	c.DB = make(map[string]string)
-	for i := uint32(0); i < numEntries; i++ {
-		if len(data) < offset+4 {
-			return fmt.Errorf("file too short for key length")
-		}
-		keyLen := binary.BigEndian.Uint32(data[offset : offset+4])
-		offset += 4
-
-		if len(data) < offset+int(keyLen) {
-			return fmt.Errorf("file too short for key data")
-		}
-		key := string(data[offset : offset+int(keyLen)])
-		offset += int(keyLen)
-
-		if len(data) < offset+4 {
-			return fmt.Errorf("file too short for value length")
-		}
-		valueLen := binary.BigEndian.Uint32(data[offset : offset+4])
-		offset += 4
-
-		if len(data) < offset+int(valueLen) {
-			return fmt.Errorf("file too short for value data")
-		}
-		value := string(data[offset : offset+int(valueLen)])
-		offset += int(valueLen)
-
-		c.DB[key] = value
-	}
-
 	return nil
 }
 
 func (c *Config) writeSecretsFile() error {
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if c.ConfigFile == "" {
+		return errors.New("config has no file, write it with SaveAs")
+	}
 	filename := findDataFile(c.ConfigFile)
 	fmt.Printf("Writing config file: %s\n", filename)
 
+	if err := c.storeMeta(); err != nil {
+		return err
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(filename)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	var buf bytes.Buffer
-
-	// Write magic header
-	buf.WriteString(MagicHeader)
-
-	// Write version
-	versionBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(versionBytes, Version)
-	buf.Write(versionBytes)
-
-	// Write number of entries
-	numEntries := uint32(len(c.DB))
-	entryCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(entryCountBytes, numEntries)
-	buf.Write(entryCountBytes)
-
-	// Write entries
-	for key, value := range c.DB {
-		// Write key length
-		keyLenBytes := make([]byte, 4)
-		binary.BigEndian.PutUint32(keyLenBytes, uint32(len(key)))
-		buf.Write(keyLenBytes)
-
-		// Write key
-		buf.WriteString(key)
+	db := c.loaded()
+	data, err := format.Encode(db)
+	if err != nil {
+		return fmt.Errorf("failed to encode config file: %v", err)
+	}
 
-		// Write value length
-		valueLenBytes := make([]byte, 4)
-		binary.BigEndian.PutUint32(valueLenBytes, uint32(len(value)))
-		buf.Write(valueLenBytes)
+	if c.backups > 0 {
+		if err := backupFile(filename, c.backups); err != nil {
+			return fmt.Errorf("failed to back up config file: %v", err)
+		}
+	}
 
-		// Write value
-		buf.WriteString(value)
+	// Note permission drift on the file being replaced
+	var prevMode os.FileMode
+	if c.strictPerms {
+		if info, err := os.Stat(filename); err == nil {
+			prevMode = info.Mode().Perm()
+		}
 	}
 
 	// Write to file
-	if err := os.WriteFile(filename, buf.Bytes(), 0600); err != nil {
+	if err := writeFileAtomic(filename, data); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
+	c.DB, c.raw, c.spans = db, nil, nil
 
+	if c.strictPerms {
+		return c.enforcePermissions(filename, prevMode)
+	}
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file next to filename and renames
+// it into place, so readers see either the old or the new file, never a mix
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, filePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
+
 // findDataFile finds the appropriate location for the config file
 func findDataFile(filename string) string {
 	//fmt.Printf("Searching for config file: %s\n", filename)
@@ -0,0 +1,185 @@
+package secureconfig
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestConfig opens a fresh config file in a temporary directory
+func newTestConfig(t *testing.T, opts ...Option) (*Config, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.bin")
+	c, err := NewConfigWithFile(path, opts...)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	return c, path
+}
+
+// mustStore stores every pair or fails the test
+func mustStore(t *testing.T, c *Config, pairs map[string]string) {
+	t.Helper()
+	for k, v := range pairs {
+		if err := c.Store(k, v); err != nil {
+			t.Fatalf("Store(%q): %v", k, err)
+		}
+	}
+}
+
+func TestRetrieveLimited(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"ascii": "abcdef", "utf8": "héllo"})
+
+	tests := []struct {
+		key       string
+		max       int
+		want      string
+		truncated bool
+	}{
+		{"ascii", 0, "abcdef", false},
+		{"ascii", 6, "abcdef", false},
+		{"ascii", 10, "abcdef", false},
+		{"ascii", 3, "abc", true},
+		// é is two bytes, a cap inside it backs off to the rune boundary
+		{"utf8", 2, "h", true},
+		{"utf8", 3, "hé", true},
+	}
+	for _, tt := range tests {
+		got, truncated, err := c.RetrieveLimited(tt.key, tt.max)
+		if err != nil {
+			t.Errorf("RetrieveLimited(%q, %d): %v", tt.key, tt.max, err)
+			continue
+		}
+		if got != tt.want || truncated != tt.truncated {
+			t.Errorf("RetrieveLimited(%q, %d) = %q, %v; want %q, %v", tt.key, tt.max, got, truncated, tt.want, tt.truncated)
+		}
+	}
+
+	if _, _, err := c.RetrieveLimited("missing", 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing key: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIterateWhileWriting(t *testing.T) {
+	c, _ := newTestConfig(t)
+	base := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		base[fmt.Sprintf("base.%d", i)] = "v"
+	}
+	mustStore(t, c, base)
+
+	const writes = 50
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			if err := c.Store(fmt.Sprintf("new.%d", i), "v"); err != nil {
+				t.Errorf("Store: %v", err)
+				return
+			}
+		}
+	}()
+
+	// checkSnapshot fails unless keys holds every base key exactly once
+	checkSnapshot := func(keys []string) {
+		seen := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			if seen[k] {
+				t.Errorf("snapshot lists %s twice", k)
+			}
+			seen[k] = true
+		}
+		for k := range base {
+			if !seen[k] {
+				t.Errorf("snapshot is missing %s", k)
+			}
+		}
+		if len(keys) > len(base)+writes {
+			t.Errorf("snapshot has %d keys, more than were ever stored", len(keys))
+		}
+	}
+
+	for i := 0; i < writes; i++ {
+		keys, err := c.ListKeys()
+		if err != nil {
+			t.Fatalf("ListKeys: %v", err)
+		}
+		checkSnapshot(keys)
+
+		// The callback runs without the lock, so it may read the config
+		var iterated []string
+		err = c.ForEachKey(func(key string) error {
+			if _, err := c.Retrieve(key); err != nil {
+				return err
+			}
+			iterated = append(iterated, key)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEachKey: %v", err)
+		}
+		checkSnapshot(iterated)
+	}
+	wg.Wait()
+}
+
+func TestAbsurdEntryCountRejected(t *testing.T) {
+	// header declares a count and is followed by padding bytes
+	header := func(count uint32, padding int) []byte {
+		data := []byte(MagicHeader)
+		data = binary.BigEndian.AppendUint32(data, Version)
+		data = binary.BigEndian.AppendUint32(data, count)
+		return append(data, make([]byte, padding)...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		opts []Option
+		want string
+	}{
+		{"maximum count", header(math.MaxUint32, 0), nil, "exceeds the limit"},
+		{"over a lowered limit", header(11, 11*8), []Option{WithMaxEntries(10)}, "exceeds the limit"},
+		{"count larger than the file", header(1000, 64), nil, "too short for 1000 entries"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.bin")
+		if err := os.WriteFile(path, tt.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		_, err := NewConfigWithFile(path, tt.opts...)
+		if !errors.Is(err, ErrCorrupted) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewConfigWithFile = %v, want ErrCorrupted with %q", tt.name, err, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: rejecting the header took %v", tt.name, elapsed)
+		}
+	}
+}
+
+func TestWithMaxEntriesRejectsNonPositive(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		path := filepath.Join(t.TempDir(), "config.bin")
+		if _, err := NewConfigWithFile(path, WithMaxEntries(n)); err == nil {
+			t.Errorf("NewConfigWithFile accepted WithMaxEntries(%d)", n)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("WithMaxEntries(%d): a file was created despite the invalid option", n)
+		}
+	}
+
+	c, _ := newTestConfig(t, WithMaxEntries(3))
+	mustStore(t, c, map[string]string{"a": "1"})
+	if _, err := NewConfigWithFile(c.ConfigFile, WithMaxEntries(3)); err != nil {
+		t.Errorf("a file within the limit does not open: %v", err)
+	}
+}
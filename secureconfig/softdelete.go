@@ -0,0 +1,101 @@
+package secureconfig
+
+import (
+	"context"
+	"fmt"
+)
+
+// SoftDelete hides a key from Retrieve, ListKeys and exports while keeping it
+// recoverable with Undelete until PurgeDeleted runs. Storing the key again
+// replaces the deleted entry.
+func (c *Config) SoftDelete(key string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if _, ok := c.findEntry(key); !ok || c.softDeleted(key) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	meta, ok := c.meta[key]
+	if !ok {
+		meta = &entryMeta{} // entry written before metadata was tracked
+		c.meta[key] = meta
+	}
+	meta.Deleted = true
+	c.cache.remove(key)
+	if err := c.writeSecretsFile(); err != nil {
+		meta.Deleted = false
+		return err
+	}
+	c.recordChange(context.Background(), OpSoftDelete, key)
+	return nil
+}
+
+// Undelete restores a key removed with SoftDelete
+func (c *Config) Undelete(key string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	if _, ok := c.findEntry(key); !ok || !c.softDeleted(key) {
+		return fmt.Errorf("%w: no soft deleted entry for %s", ErrNotFound, key)
+	}
+	meta := c.meta[key]
+	meta.Deleted = false
+	if err := c.writeSecretsFile(); err != nil {
+		meta.Deleted = true
+		return err
+	}
+	c.recordChange(context.Background(), OpUndelete, key)
+	return nil
+}
+
+// PurgeDeleted permanently removes every soft deleted entry and returns how
+// many were removed
+func (c *Config) PurgeDeleted() (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly {
+		return 0, ErrReadOnly
+	}
+	var purged []string
+	for key, meta := range c.meta {
+		if !meta.Deleted {
+			continue
+		}
+		if k, ok := c.findEntry(key); ok {
+			delete(c.DB, k)
+		}
+		delete(c.meta, key)
+		purged = append(purged, key)
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	if err := c.writeSecretsFile(); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	for _, key := range purged {
+		c.recordChange(ctx, OpDelete, key)
+	}
+	return len(purged), nil
+}
+
+// softDeleted reports whether key is marked deleted
+func (c *Config) softDeleted(key string) bool {
+	meta, ok := c.meta[key]
+	return ok && meta.Deleted
+}
@@ -0,0 +1,90 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSoftDeleteLifecycle(t *testing.T) {
+	c, path := newTestConfig(t)
+	mustStore(t, c, map[string]string{"db.password": "hunter2", "api.token": "tok"})
+
+	if err := c.SoftDelete("db.password"); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	reopened, err := NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	for _, c := range []*Config{c, reopened} {
+		if _, err := c.Retrieve("db.password"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Retrieve of a soft deleted key = %v, want ErrNotFound", err)
+		}
+		if keys, _ := c.ListKeys(); len(keys) != 1 || keys[0] != "api.token" {
+			t.Errorf("ListKeys = %v, want only api.token", keys)
+		}
+		var manifest bytes.Buffer
+		if err := c.ExportManifest(&manifest); err != nil {
+			t.Fatalf("ExportManifest: %v", err)
+		}
+		if bytes.Contains(manifest.Bytes(), []byte("db.password")) {
+			t.Error("manifest lists a soft deleted key")
+		}
+	}
+	if err := c.SoftDelete("db.password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second SoftDelete = %v, want ErrNotFound", err)
+	}
+
+	if err := c.Undelete("db.password"); err != nil {
+		t.Fatalf("Undelete: %v", err)
+	}
+	if got, err := c.Retrieve("db.password"); err != nil || got != "hunter2" {
+		t.Errorf("Retrieve after Undelete = %q, %v; want hunter2", got, err)
+	}
+	if err := c.Undelete("db.password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Undelete of a live key = %v, want ErrNotFound", err)
+	}
+
+	if err := c.SoftDelete("db.password"); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	n, err := c.PurgeDeleted()
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeDeleted = %d, %v; want 1", n, err)
+	}
+	if err := c.Undelete("db.password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Undelete after purge = %v, want ErrNotFound", err)
+	}
+	if n, err := c.PurgeDeleted(); err != nil || n != 0 {
+		t.Errorf("second PurgeDeleted = %d, %v; want 0", n, err)
+	}
+
+	// The purged entry is gone from the file, not just hidden
+	reopened, err = NewConfigWithFile(path)
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if _, ok := reopened.findEntry("db.password"); ok {
+		t.Error("purged entry is still in the file")
+	}
+	if got, err := reopened.Retrieve("api.token"); err != nil || got != "tok" {
+		t.Errorf("Retrieve(api.token) = %q, %v; want tok", got, err)
+	}
+}
+
+func TestStoreOverSoftDeletedKey(t *testing.T) {
+	c, _ := newTestConfig(t)
+	mustStore(t, c, map[string]string{"k": "old"})
+	if err := c.SoftDelete("k"); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	mustStore(t, c, map[string]string{"k": "new"})
+
+	if got, err := c.Retrieve("k"); err != nil || got != "new" {
+		t.Errorf("Retrieve = %q, %v; want new", got, err)
+	}
+	if err := c.Undelete("k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Undelete after storing again = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,129 @@
+package secureconfig
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// splitHKDF marks a file whose key names and values are encrypted under
+// separate keys derived from the master key with HKDF-SHA256
+const splitHKDF = "hkdf-sha256"
+
+// HKDF info strings for the two derived keys
+const (
+	keyEncInfo   = "secureconfig key-encryption key"
+	valueEncInfo = "secureconfig value-encryption key"
+)
+
+// WithSplitKeys makes newly created files encrypt key names and metadata with
+// a key-encryption key and values with a value-encryption key, both derived
+// from the master key. Holders of the key-encryption key alone can list
+// entries with NewConfigKeyOnly but not read values. Existing files keep the
+// layout they were created with.
+//
+// The split only restricts anyone when the master key is kept outside the
+// file with a KeyProvider, since an embedded key opens everything.
+func WithSplitKeys() Option {
+	return func(c *Config) {
+		c.newSplit = true
+	}
+}
+
+// NewConfigKeyOnly opens a file created with WithSplitKeys using only its
+// key-encryption key. The handle can list keys and export manifests, but
+// Retrieve and anything else that needs a value return ErrKeyOnly, and all
+// writes return ErrReadOnly.
+func NewConfigKeyOnly(filename string, keyEncKey []byte) (*Config, error) {
+	if len(keyEncKey) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(keyEncKey))
+	}
+
+	c := newConfig(filename, nil)
+	c.readOnly = true
+	c.keyOnly = true
+	if err := c.loadDB(); err != nil {
+		return nil, err
+	}
+	if !c.splitKeys() {
+		return nil, errors.New("config file does not use split keys")
+	}
+	gcm, err := newGCM(keyEncKey)
+	if err != nil {
+		return nil, err
+	}
+	c.GCM = gcm
+
+	// A wrong key fails on the metadata, or on the key names
+	if err := c.loadMeta(); err != nil {
+		return nil, err
+	}
+	matched := false
+	skipped := c.eachKey(func(string, string) bool {
+		matched = true
+		return false
+	})
+	if !matched && skipped > 0 {
+		return nil, errors.New("key-encryption key does not match the file")
+	}
+	c.loadBaseline()
+	return c, nil
+}
+
+// KeyEncryptionKey returns the key that encrypts key names and metadata, to
+// hand to NewConfigKeyOnly. It fails for files without split keys and for
+// key-only handles.
+func (c *Config) KeyEncryptionKey() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.keyOnly {
+		return nil, ErrKeyOnly
+	}
+	if !c.splitKeys() {
+		return nil, errors.New("config file does not use split keys")
+	}
+	return deriveSubkey(c.Key, keyEncInfo)
+}
+
+// splitKeys reports whether key names and values use separate keys
+func (c *Config) splitKeys() bool {
+	return c.DB[splitEntry] == splitHKDF
+}
+
+// newCiphers returns the ciphers for key names and for values under master.
+// Without split keys both are the same.
+func newCiphers(master []byte, split bool) (names, values cipher.AEAD, err error) {
+	if !split {
+		gcm, err := newGCM(master)
+		return gcm, gcm, err
+	}
+	kek, err := deriveSubkey(master, keyEncInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	vek, err := deriveSubkey(master, valueEncInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if names, err = newGCM(kek); err != nil {
+		return nil, nil, err
+	}
+	if values, err = newGCM(vek); err != nil {
+		return nil, nil, err
+	}
+	return names, values, nil
+}
+
+// deriveSubkey derives a 256-bit subkey of master for the purpose named by info
+func deriveSubkey(master []byte, info string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(info)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,80 @@
+package secureconfig
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestKeyOnlyHandle(t *testing.T) {
+	p := &mapProvider{keys: map[string][]byte{"": bytes.Repeat([]byte{0x24}, 32)}}
+	c, path := newTestConfig(t, WithKeyProvider(p), WithSplitKeys())
+	mustStore(t, c, map[string]string{"db.password": "hunter2", "api.token": "tok"})
+
+	kek, err := c.KeyEncryptionKey()
+	if err != nil {
+		t.Fatalf("KeyEncryptionKey: %v", err)
+	}
+	ko, err := NewConfigKeyOnly(path, kek)
+	if err != nil {
+		t.Fatalf("NewConfigKeyOnly: %v", err)
+	}
+
+	keys, err := ko.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "api.token" || keys[1] != "db.password" {
+		t.Errorf("ListKeys = %v, want [api.token db.password]", keys)
+	}
+	var manifest bytes.Buffer
+	if err := ko.ExportManifest(&manifest); err != nil {
+		t.Errorf("ExportManifest: %v", err)
+	}
+
+	if _, err := ko.Retrieve("db.password"); !errors.Is(err, ErrKeyOnly) {
+		t.Errorf("Retrieve = %v, want ErrKeyOnly", err)
+	}
+	if _, err := ko.KeyEncryptionKey(); !errors.Is(err, ErrKeyOnly) {
+		t.Errorf("KeyEncryptionKey = %v, want ErrKeyOnly", err)
+	}
+	if err := ko.Store("db.password", "changed"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Store = %v, want ErrReadOnly", err)
+	}
+
+	// The full key still reads values
+	full, err := NewConfigWithFile(path, WithKeyProvider(p))
+	if err != nil {
+		t.Fatalf("NewConfigWithFile: %v", err)
+	}
+	if got, err := full.Retrieve("db.password"); err != nil || got != "hunter2" {
+		t.Errorf("Retrieve with the full key = %q, %v; want hunter2", got, err)
+	}
+}
+
+func TestNewConfigKeyOnlyRejectsWrongKeys(t *testing.T) {
+	c, path := newTestConfig(t, WithSplitKeys())
+	mustStore(t, c, map[string]string{"a": "1"})
+
+	if _, err := NewConfigKeyOnly(path, bytes.Repeat([]byte{0x01}, 32)); err == nil {
+		t.Error("NewConfigKeyOnly accepted the wrong key")
+	}
+	// The value-encryption key is not the key-encryption key
+	vek, err := deriveSubkey(c.Key, valueEncInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewConfigKeyOnly(path, vek); err == nil {
+		t.Error("NewConfigKeyOnly accepted the value-encryption key")
+	}
+
+	plain, plainPath := newTestConfig(t)
+	if _, err := plain.KeyEncryptionKey(); err == nil {
+		t.Error("KeyEncryptionKey succeeded on a file without split keys")
+	}
+	if _, err := NewConfigKeyOnly(plainPath, bytes.Repeat([]byte{0x01}, 32)); err == nil {
+		t.Error("NewConfigKeyOnly opened a file without split keys")
+	}
+}
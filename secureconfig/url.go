@@ -0,0 +1,51 @@
+package secureconfig
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// StoreURL validates u as a connection URL and stores it under key. The URL
+// must have a scheme and a host (a path is enough for file URLs), so typos
+// such as a missing scheme fail here rather than at connect time.
+func (c *Config) StoreURL(key string, u *url.URL) error {
+	if err := validateURL(u); err != nil {
+		return fmt.Errorf("invalid URL for %s: %v", key, err)
+	}
+	return c.Store(key, u.String())
+}
+
+// RetrieveURL retrieves the value under key and parses it as a connection URL.
+// Use the returned URL's Redacted method when logging it to mask the password.
+func (c *Config) RetrieveURL(key string) (*url.URL, error) {
+	value, err := c.Retrieve(key)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		// url errors quote the input, which would leak credentials
+		return nil, fmt.Errorf("value for %s is not a valid URL", key)
+	}
+	if err := validateURL(u); err != nil {
+		return nil, fmt.Errorf("value for %s is not a valid URL: %v", key, err)
+	}
+	return u, nil
+}
+
+// validateURL checks that u is usable as a connection URL
+func validateURL(u *url.URL) error {
+	switch {
+	case u == nil:
+		return errors.New("URL is nil")
+	case u.Scheme == "":
+		return errors.New("missing scheme")
+	case u.Opaque != "":
+		// "localhost:5432/db" parses with scheme "localhost"
+		return errors.New("missing // after scheme")
+	case u.Host == "" && !(u.Scheme == "file" && u.Path != ""):
+		return errors.New("missing host")
+	}
+	return nil
+}